@@ -0,0 +1,97 @@
+package requestclient
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Body content types
+const (
+	ContentTypeBinary = "application/octet-stream"
+	ContentTypeText   = "text/plain; charset=utf-8"
+	ContentTypeForm   = "application/x-www-form-urlencoded"
+	ContentTypeJSON   = "application/json; charset=utf-8"
+)
+
+// Body - encoded request body with content type and length
+type Body struct {
+	Reader      io.Reader
+	ContentType string
+
+	// Length of Reader in bytes, -1 if unknown
+	Length int64
+}
+
+// NewBody - encodes body according to its kind:
+//
+//	nil        - no body
+//	[]byte     - application/octet-stream
+//	string     - text/plain
+//	url.Values - application/x-www-form-urlencoded
+//	*Multipart - multipart/form-data, streamed
+//	io.Reader  - application/octet-stream, length inferred when possible
+//	other      - application/json, value is encoded with registered JSON Codec
+func NewBody(body interface{}) (b *Body, err error) {
+	switch v := body.(type) {
+	case nil:
+		return &Body{}, nil
+	case []byte:
+		return &Body{bytes.NewReader(v), ContentTypeBinary, int64(len(v))}, nil
+	case string:
+		return &Body{strings.NewReader(v), ContentTypeText, int64(len(v))}, nil
+	case url.Values:
+		s := v.Encode()
+		return &Body{strings.NewReader(s), ContentTypeForm, int64(len(s))}, nil
+	case *Multipart:
+		return v.Body()
+	case io.Reader:
+		return &Body{v, ContentTypeBinary, readerLength(v)}, nil
+	}
+	return NewEncodedBody(ContentTypeJSON, body)
+}
+
+// NewBodyRequest - returns a new Request with body encoded by NewBody,
+// ContentLength and Content-Type are set from the encoded body
+func (r *RequestClient) NewBodyRequest(method string, u *url.URL, body interface{}) (*http.Request, error) {
+	b, err := NewBody(body)
+	if err != nil {
+		return nil, err
+	}
+	return r.newBodyRequest(method, u, b), nil
+}
+
+func (r *RequestClient) newBodyRequest(method string, u *url.URL, b *Body) *http.Request {
+	req := r.NewRequest(method, u, b.Reader)
+	if b.Reader != nil {
+		setContentLength(req, b.Length)
+	}
+	if b.ContentType != "" {
+		req.Header.Set("Content-Type", b.ContentType)
+	}
+	return req
+}
+
+// readerLength - returns number of unread bytes in r or -1 if unknown
+func readerLength(r io.Reader) int64 {
+	switch v := r.(type) {
+	case interface {
+		Len() int
+	}:
+		return int64(v.Len())
+	case *os.File:
+		fi, err := v.Stat()
+		if err != nil || !fi.Mode().IsRegular() {
+			return -1
+		}
+		offset, err := v.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		return fi.Size() - offset
+	}
+	return -1
+}
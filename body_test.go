@@ -0,0 +1,74 @@
+package requestclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestNewBody(t *testing.T) {
+	cases := []struct {
+		body        interface{}
+		contentType string
+		length      int64
+	}{
+		{[]byte("abc"), ContentTypeBinary, 3},
+		{"abcd", ContentTypeText, 4},
+		{url.Values{"a": {"1"}}, ContentTypeForm, 3},
+		{strings.NewReader("abcde"), ContentTypeBinary, 5},
+		{map[string]int{"a": 1}, ContentTypeJSON, 7},
+	}
+	for _, c := range cases {
+		b, err := NewBody(c.body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.ContentType != c.contentType || b.Length != c.length {
+			t.Errorf("NewBody(%T) got {%s %d} expected {%s %d}",
+				c.body, b.ContentType, b.Length, c.contentType, c.length)
+		}
+	}
+}
+
+func TestPOST(t *testing.T) {
+	client := New(nil)
+	u, _ := url.Parse("http://www.example.com")
+	req, err := client.POST(u, url.Values{"a": {"1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.ContentLength != 3 {
+		t.Errorf("Expected ContentLength 3 got %d", req.ContentLength)
+	}
+	if got := client.DefaultHeaders().Get("Content-Type"); got != "" {
+		t.Errorf("Expected client headers untouched got Content-Type %s", got)
+	}
+}
+
+func TestEmptyBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TransferEncoding) > 0 || r.ContentLength != 0 {
+			t.Errorf("Expected empty body with ContentLength 0 got %v %d", r.TransferEncoding, r.ContentLength)
+		}
+	}))
+	defer ts.Close()
+	client := New(nil)
+	u, _ := url.Parse(ts.URL)
+
+	post, err := client.POST(u, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, req := range []*http.Request{post, client.NewRequest(PUT, u, strings.NewReader(""))} {
+		if req.Body != http.NoBody {
+			t.Errorf("Expected http.NoBody got %T", req.Body)
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+}
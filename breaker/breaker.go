@@ -0,0 +1,311 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Breaker default values
+const (
+	DefaultWindow           = 10 * time.Second
+	DefaultBuckets          = 10
+	DefaultMinRequests      = 20
+	DefaultFailureRatio     = 0.5
+	DefaultOpenTimeout      = 30 * time.Second
+	DefaultHalfOpenRequests = 1
+)
+
+// ErrOpen - request was rejected by open circuit breaker
+var ErrOpen = errors.New("Circuit breaker is open")
+
+// OpenError - request was rejected, breaker of Key is open until Until
+type OpenError struct {
+	Key   string
+	State State
+	Until time.Time
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("Circuit breaker for %s is %s", e.Key, e.State)
+}
+
+// Is - OpenError matches ErrOpen
+func (e *OpenError) Is(target error) bool {
+	return target == ErrOpen
+}
+
+// State - circuit breaker state
+type State int
+
+// Circuit breaker states
+const (
+	Closed State = iota
+	Open
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// Config - circuit breaker options
+type Config struct {
+	// Window - sliding window failure ratio is computed over, divided
+	// into Buckets
+	Window  time.Duration
+	Buckets int
+
+	// MinRequests - number of requests in window before breaker may open
+	MinRequests int
+
+	// FailureRatio - failed to total requests ratio in window opening breaker
+	FailureRatio float64
+
+	// OpenTimeout - time breaker stays open before letting probes through
+	OpenTimeout time.Duration
+
+	// HalfOpenRequests - number of probes let through in half-open state,
+	// breaker closes once all succeed and opens on first failure
+	HalfOpenRequests int
+
+	// Key - returns breaker key for a request, HostKey if nil
+	Key func(*http.Request) string
+
+	// IsFailure - reports whether request outcome counts as failure,
+	// DefaultIsFailure if nil. Requests failing after their context is
+	// done are never counted.
+	IsFailure func(*http.Response, error) bool
+
+	// OnStateChange, if non-nil, is called on every state change
+	OnStateChange func(key string, from, to State)
+}
+
+// NewConfig - Config initialized with default values
+func NewConfig() *Config {
+	return &Config{
+		Window:           DefaultWindow,
+		Buckets:          DefaultBuckets,
+		MinRequests:      DefaultMinRequests,
+		FailureRatio:     DefaultFailureRatio,
+		OpenTimeout:      DefaultOpenTimeout,
+		HalfOpenRequests: DefaultHalfOpenRequests,
+	}
+}
+
+// HostKey - breaker per host
+func HostKey(req *http.Request) string {
+	return req.URL.Hostname()
+}
+
+// HostPortKey - breaker per host:port, default port derived from scheme
+func HostPortKey(req *http.Request) string {
+	port := req.URL.Port()
+	if port == "" {
+		port = "80"
+		if req.URL.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(req.URL.Hostname(), port)
+}
+
+// DefaultIsFailure - errors and 5xx responses are failures, cancelled
+// requests are not
+func DefaultIsFailure(res *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+	return res.StatusCode >= 500
+}
+
+// Breaker - circuit breaker of a single key
+type Breaker struct {
+	key    string
+	config *Config
+
+	mu       sync.Mutex
+	state    State
+	buckets  []bucket
+	openedAt time.Time
+	probes   int    // in-flight half-open probes
+	passed   int    // succeeded half-open probes
+	gen      uint64 // incremented on every state change
+	changes  []change
+}
+
+// Ticket - request let through by Allow, outcomes of tickets admitted
+// before the latest state change are ignored
+type Ticket struct {
+	gen   uint64
+	probe bool
+}
+
+type change struct {
+	from, to State
+}
+
+type bucket struct {
+	start             time.Time
+	success, failures int
+}
+
+// New - returns closed Breaker for key
+func New(key string, c *Config) *Breaker {
+	buckets := c.Buckets
+	if buckets <= 0 {
+		buckets = 1
+	}
+	return &Breaker{
+		key:     key,
+		config:  c,
+		buckets: make([]bucket, buckets),
+	}
+}
+
+// State - current breaker state
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.unlock()
+	b.advance(time.Now())
+	return b.state
+}
+
+// Allow - returns *OpenError if request must not be sent, otherwise caller
+// must report request outcome of ticket with Done or Release
+func (b *Breaker) Allow() (Ticket, error) {
+	b.mu.Lock()
+	defer b.unlock()
+	now := time.Now()
+	b.advance(now)
+	t := Ticket{gen: b.gen}
+	switch b.state {
+	case Open:
+		return t, &OpenError{Key: b.key, State: Open, Until: b.openedAt.Add(b.config.OpenTimeout)}
+	case HalfOpen:
+		if b.probes+b.passed >= b.config.HalfOpenRequests {
+			return t, &OpenError{Key: b.key, State: HalfOpen}
+		}
+		b.probes++
+		t.probe = true
+	}
+	return t, nil
+}
+
+// Release - frees ticket without outcome, e.g. request was cancelled by
+// caller
+func (b *Breaker) Release(t Ticket) {
+	b.mu.Lock()
+	defer b.unlock()
+	if t.probe && t.gen == b.gen {
+		b.probes--
+	}
+}
+
+// Done - records outcome of request let through by Allow, only probes
+// decide half-open outcome
+func (b *Breaker) Done(t Ticket, failure bool) {
+	b.mu.Lock()
+	defer b.unlock()
+	if t.gen != b.gen {
+		return
+	}
+	now := time.Now()
+	if t.probe {
+		b.probes--
+		if failure {
+			b.setState(Open, now)
+			return
+		}
+		b.passed++
+		if b.passed >= b.config.HalfOpenRequests {
+			b.setState(Closed, now)
+		}
+		return
+	}
+	if b.state != Closed {
+		return
+	}
+	cur := b.bucket(now)
+	if failure {
+		cur.failures++
+	} else {
+		cur.success++
+	}
+	var total, failures int
+	for _, bk := range b.buckets {
+		if now.Sub(bk.start) < b.config.Window {
+			total += bk.success + bk.failures
+			failures += bk.failures
+		}
+	}
+	if total >= b.config.MinRequests && total > 0 &&
+		float64(failures)/float64(total) >= b.config.FailureRatio {
+		b.setState(Open, now)
+	}
+}
+
+// advance - moves open breaker to half-open once OpenTimeout passed
+func (b *Breaker) advance(now time.Time) {
+	if b.state == Open && now.Sub(b.openedAt) >= b.config.OpenTimeout {
+		b.setState(HalfOpen, now)
+	}
+}
+
+// bucket - returns bucket of now, resetting it when reused
+func (b *Breaker) bucket(now time.Time) *bucket {
+	width := b.config.Window / time.Duration(len(b.buckets))
+	if width <= 0 {
+		width = time.Nanosecond
+	}
+	slot := now.Truncate(width)
+	cur := &b.buckets[int(slot.UnixNano()/int64(width))%len(b.buckets)]
+	if !cur.start.Equal(slot) {
+		*cur = bucket{start: slot}
+	}
+	return cur
+}
+
+func (b *Breaker) setState(to State, now time.Time) {
+	from := b.state
+	b.state = to
+	b.probes, b.passed = 0, 0
+	b.gen++
+	switch to {
+	case Open:
+		b.openedAt = now
+	case Closed:
+		for i := range b.buckets {
+			b.buckets[i] = bucket{}
+		}
+	}
+	if from != to {
+		b.changes = append(b.changes, change{from, to})
+	}
+}
+
+// unlock - unlocks breaker and reports state changes, so OnStateChange
+// may query breaker state
+func (b *Breaker) unlock() {
+	changes := b.changes
+	b.changes = nil
+	b.mu.Unlock()
+	if b.config.OnStateChange == nil {
+		return
+	}
+	for _, c := range changes {
+		b.config.OnStateChange(b.key, c.from, c.to)
+	}
+}
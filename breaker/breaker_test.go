@@ -0,0 +1,125 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBreakerStates(t *testing.T) {
+	c := NewConfig()
+	c.MinRequests = 4
+	c.OpenTimeout = 20 * time.Millisecond
+	var changes []State
+	c.OnStateChange = func(key string, from, to State) {
+		changes = append(changes, to)
+	}
+	b := New("example.com", c)
+
+	for _, failure := range []bool{false, true, false, true} {
+		ticket, err := b.Allow()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.Done(ticket, failure)
+	}
+	if s := b.State(); s != Open {
+		t.Fatalf("Expected open breaker got %s", s)
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Errorf("Expected ErrOpen got %v", err)
+	}
+
+	time.Sleep(c.OpenTimeout)
+	probe, err := b.Allow()
+	if err != nil {
+		t.Fatalf("Expected half-open probe to pass got %v", err)
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Errorf("Expected second probe to be rejected got %v", err)
+	}
+	b.Done(probe, false)
+	if s := b.State(); s != Closed {
+		t.Errorf("Expected closed breaker got %s", s)
+	}
+	expected := []State{Open, HalfOpen, Closed}
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected state changes %v got %v", expected, changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("Expected state changes %v got %v", expected, changes)
+		}
+	}
+}
+
+func TestNewSetKeepsConfig(t *testing.T) {
+	c := NewConfig()
+	s := NewSet(c)
+	if c.Key != nil || c.IsFailure != nil {
+		t.Error("Expected caller's config to be left untouched")
+	}
+	if s.Get("example.com").config.IsFailure == nil {
+		t.Error("Expected set config to default IsFailure")
+	}
+}
+
+func TestBreakerIgnoresEarlierRequests(t *testing.T) {
+	c := NewConfig()
+	c.MinRequests = 1
+	c.OpenTimeout = 10 * time.Millisecond
+	b := New("example.com", c)
+
+	slow, _ := b.Allow() // admitted while closed, finishes after half-open
+	failed, _ := b.Allow()
+	b.Done(failed, true)
+	time.Sleep(c.OpenTimeout)
+	if s := b.State(); s != HalfOpen {
+		t.Fatalf("Expected half-open breaker got %s", s)
+	}
+	b.Done(slow, false)
+	if s := b.State(); s != HalfOpen || b.probes != 0 {
+		t.Fatalf("Expected late request to be ignored got %s with %d probes", s, b.probes)
+	}
+
+	probe, err := b.Allow()
+	if err != nil {
+		t.Fatalf("Expected probe to pass got %v", err)
+	}
+	b.Release(probe)
+	if probe, err = b.Allow(); err != nil {
+		t.Fatalf("Expected released probe slot to be reused got %v", err)
+	}
+	b.Done(probe, false)
+	if s := b.State(); s != Closed {
+		t.Errorf("Expected probe to close breaker got %s", s)
+	}
+}
+
+func TestTransportIgnoresCancelled(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+	c := NewConfig()
+	c.MinRequests = 1
+	s := NewSet(c)
+	client := &http.Client{Transport: s.Transport(http.DefaultTransport)}
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL, nil)
+		if _, err := client.Do(req); err == nil {
+			t.Fatal("Expected cancelled request to fail")
+		}
+		cancel()
+	}
+	if !DefaultIsFailure(nil, errors.New("refused")) || DefaultIsFailure(nil, context.Canceled) {
+		t.Error("Expected errors other than cancellation to be failures")
+	}
+	if st := s.State("127.0.0.1"); st != Closed {
+		t.Errorf("Expected caller timeouts not to open breaker got %s", st)
+	}
+}
@@ -0,0 +1,101 @@
+package breaker
+
+import (
+	"net/http"
+	"sync"
+)
+
+// Set - circuit breakers keyed by Config.Key, created on first use
+type Set struct {
+	config *Config
+
+	mu       sync.RWMutex
+	breakers map[string]*Breaker
+}
+
+// NewSet - returns empty breaker set sharing copy of config
+func NewSet(config *Config) *Set {
+	c := *config
+	if c.Key == nil {
+		c.Key = HostKey
+	}
+	if c.IsFailure == nil {
+		c.IsFailure = DefaultIsFailure
+	}
+	return &Set{
+		config:   &c,
+		breakers: make(map[string]*Breaker),
+	}
+}
+
+// Get - returns breaker of key, creating closed one if missing
+func (s *Set) Get(key string) *Breaker {
+	s.mu.RLock()
+	b, ok := s.breakers[key]
+	s.mu.RUnlock()
+	if ok {
+		return b
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b, ok = s.breakers[key]; !ok {
+		b = New(key, s.config)
+		s.breakers[key] = b
+	}
+	return b
+}
+
+// State - returns breaker state of key, Closed if none was created yet
+func (s *Set) State(key string) State {
+	s.mu.RLock()
+	b, ok := s.breakers[key]
+	s.mu.RUnlock()
+	if !ok {
+		return Closed
+	}
+	return b.State()
+}
+
+// States - returns states of all breakers by key
+func (s *Set) States() map[string]State {
+	s.mu.RLock()
+	breakers := make(map[string]*Breaker, len(s.breakers))
+	for k, b := range s.breakers {
+		breakers[k] = b
+	}
+	s.mu.RUnlock()
+	states := make(map[string]State, len(breakers))
+	for k, b := range breakers {
+		states[k] = b.State()
+	}
+	return states
+}
+
+// Transport - returns RoundTripper failing fast with *OpenError while
+// breaker of request key is open
+func (s *Set) Transport(next http.RoundTripper) http.RoundTripper {
+	return &transport{set: s, next: next}
+}
+
+type transport struct {
+	set  *Set
+	next http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	b := t.set.Get(t.set.config.Key(req))
+	ticket, err := b.Allow()
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	res, err := t.next.RoundTrip(req)
+	if err != nil && req.Context().Err() != nil {
+		b.Release(ticket) // caller gave up, says nothing of backend
+		return res, err
+	}
+	b.Done(ticket, t.set.config.IsFailure(res, err))
+	return res, err
+}
@@ -0,0 +1,223 @@
+package requestclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RequestBuilder - fluent http.Request builder on top of NewRequest, errors
+// are returned by Build, Do or RoundTrip
+type RequestBuilder struct {
+	client *RequestClient
+	method string
+	rawURL string
+
+	params  map[string]string
+	query   url.Values
+	header  http.Header
+	cookies []*http.Cookie
+
+	user, password string
+	basicAuth      bool
+
+	body        interface{}
+	hasBody     bool
+	contentType string
+
+	ctx     context.Context
+	timeout time.Duration
+}
+
+// Request - returns RequestBuilder for given method and raw URL, URL may
+// contain path parameters in {name} form, see Param
+func (r *RequestClient) Request(method, rawURL string) *RequestBuilder {
+	return &RequestBuilder{
+		client: r,
+		method: method,
+		rawURL: rawURL,
+		params: make(map[string]string),
+		query:  make(url.Values),
+		header: make(http.Header),
+		ctx:    context.Background(),
+	}
+}
+
+// Param - replaces {name} in URL path with escaped value
+func (b *RequestBuilder) Param(name, value string) *RequestBuilder {
+	b.params[name] = value
+	return b
+}
+
+// Query - adds query values to URL, repeated calls append values
+func (b *RequestBuilder) Query(key string, values ...string) *RequestBuilder {
+	for _, v := range values {
+		b.query.Add(key, v)
+	}
+	return b
+}
+
+// Header - adds request header value, client default values of the
+// same key are replaced
+func (b *RequestBuilder) Header(key, value string) *RequestBuilder {
+	key = http.CanonicalHeaderKey(key)
+	b.header[key] = append(b.header[key], value)
+	return b
+}
+
+// DelHeader - removes client default header from request
+func (b *RequestBuilder) DelHeader(key string) *RequestBuilder {
+	b.header[http.CanonicalHeaderKey(key)] = nil
+	return b
+}
+
+// Cookie - adds cookie to request
+func (b *RequestBuilder) Cookie(c *http.Cookie) *RequestBuilder {
+	b.cookies = append(b.cookies, c)
+	return b
+}
+
+// BasicAuth - sets request Authorization header with basic auth credentials
+func (b *RequestBuilder) BasicAuth(user, password string) *RequestBuilder {
+	b.user, b.password, b.basicAuth = user, password, true
+	return b
+}
+
+// Body - sets request body, encoded with NewBody
+func (b *RequestBuilder) Body(body interface{}) *RequestBuilder {
+	b.body, b.hasBody, b.contentType = body, true, ""
+	return b
+}
+
+// Encode - sets request body to v encoded with codec registered for
+// content type, regardless of v kind
+func (b *RequestBuilder) Encode(contentType string, v interface{}) *RequestBuilder {
+	b.body, b.hasBody, b.contentType = v, true, contentType
+	return b
+}
+
+// JSON - sets request body to JSON encoded v
+func (b *RequestBuilder) JSON(v interface{}) *RequestBuilder {
+	return b.Encode(ContentTypeJSON, v)
+}
+
+// XML - sets request body to XML encoded v
+func (b *RequestBuilder) XML(v interface{}) *RequestBuilder {
+	return b.Encode(ContentTypeXML, v)
+}
+
+// Form - sets request body to form encoded values
+func (b *RequestBuilder) Form(values url.Values) *RequestBuilder {
+	return b.Body(values)
+}
+
+// Context - sets request context, cancellation and deadline of ctx reach
+// retries, DNS lookup and dialing
+func (b *RequestBuilder) Context(ctx context.Context) *RequestBuilder {
+	b.ctx = ctx
+	return b
+}
+
+// Timeout - limits the entire request, including reading of response body,
+// clock starts when request is sent by Do or RoundTrip
+func (b *RequestBuilder) Timeout(d time.Duration) *RequestBuilder {
+	b.timeout = d
+	return b
+}
+
+// Build - returns formed http.Request usable with Do and RoundTrip,
+// Timeout is not applied, set a deadline on request context instead
+func (b *RequestBuilder) Build() (*http.Request, error) {
+	return b.build()
+}
+
+// Do - builds request and sends it with RequestClient.Do
+func (b *RequestBuilder) Do() (*http.Response, error) {
+	return b.send(b.client.Do)
+}
+
+// DoInto - builds request, sends it with RequestClient.Do and decodes
+// response into v, see DecodeResponse
+func (b *RequestBuilder) DoInto(v interface{}, expected ...int) error {
+	res, err := b.Do()
+	if err != nil {
+		return err
+	}
+	return DecodeResponse(res, v, expected...)
+}
+
+// RoundTrip - builds request and sends it with RequestClient.RoundTrip
+func (b *RequestBuilder) RoundTrip() (*http.Response, error) {
+	return b.send(b.client.RoundTrip)
+}
+
+func (b *RequestBuilder) send(fn func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	req, err := b.build()
+	if err != nil {
+		return nil, err
+	}
+	if b.timeout <= 0 {
+		return fn(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), b.timeout)
+	res, err := fn(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	res.Body = &releaseBody{ReadCloser: res.Body, release: cancel}
+	return res, nil
+}
+
+// build - forms request of builder state, without Timeout
+func (b *RequestBuilder) build() (req *http.Request, err error) {
+	rawURL := b.rawURL
+	for name, value := range b.params {
+		rawURL = strings.Replace(rawURL, "{"+name+"}", url.PathEscape(value), -1)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if len(b.query) > 0 {
+		q := u.Query()
+		for k, v := range b.query {
+			q[k] = append(q[k], v...)
+		}
+		u.RawQuery = q.Encode()
+	}
+	body := &Body{}
+	switch {
+	case b.contentType != "":
+		body, err = NewEncodedBody(b.contentType, b.body)
+	case b.hasBody:
+		body, err = NewBody(b.body)
+	}
+	if err != nil {
+		return nil, err
+	}
+	req = b.client.newBodyRequest(b.method, u, body).WithContext(b.ctx)
+	OverrideHeader(req.Header, b.header)
+	for _, c := range b.cookies {
+		req.AddCookie(c)
+	}
+	if b.basicAuth {
+		req.SetBasicAuth(b.user, b.password)
+	}
+	return req, nil
+}
+
+// releaseBody - releases request resources once response body is closed
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
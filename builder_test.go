@@ -0,0 +1,77 @@
+package requestclient
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRequestBuilder(t *testing.T) {
+	client := New(nil)
+	req, err := client.Request(PUT, "http://www.example.com/users/{id}?a=1").
+		Param("id", "a b").
+		Query("a", "2").
+		Query("b", "3", "4").
+		Header("X-Trace", "1").
+		DelHeader("Connection").
+		Cookie(&http.Cookie{Name: "session", Value: "s"}).
+		BasicAuth("user", "pass").
+		JSON(map[string]int{"a": 1}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := req.URL.EscapedPath(); got != "/users/a%20b" {
+		t.Errorf("Expected path /users/a%%20b got %s", got)
+	}
+	if got := req.URL.RawQuery; got != "a=1&a=2&b=3&b=4" {
+		t.Errorf("Expected query a=1&a=2&b=3&b=4 got %s", got)
+	}
+	if got := req.Header.Get("Content-Type"); got != ContentTypeJSON {
+		t.Errorf("Expected Content-Type %s got %s", ContentTypeJSON, got)
+	}
+	if got := req.Header.Get("Connection"); got != "" {
+		t.Errorf("Expected Connection to be deleted got %s", got)
+	}
+	if _, err := req.Cookie("session"); err != nil {
+		t.Error(err)
+	}
+	if user, pass, ok := req.BasicAuth(); !ok || user != "user" || pass != "pass" {
+		t.Errorf("Expected basic auth user:pass got %s:%s", user, pass)
+	}
+	if req.ContentLength != 7 {
+		t.Errorf("Expected ContentLength 7 got %d", req.ContentLength)
+	}
+}
+
+func TestRequestBuilderParseError(t *testing.T) {
+	client := New(nil)
+	if _, err := client.Request(GET, "http://[::1").Build(); err == nil {
+		t.Error("Expected URL parse error")
+	}
+}
+
+func TestRequestBuilderTimeout(t *testing.T) {
+	client := New(nil)
+	client.Use(func(next RoundTripper) RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			deadline, ok := req.Context().Deadline()
+			if !ok || time.Until(deadline) > time.Minute {
+				t.Errorf("Expected request deadline within timeout got %v %v", deadline, ok)
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		})
+	})
+	req, err := client.Request(GET, "http://www.example.com").Timeout(time.Minute).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := req.Context().Deadline(); ok {
+		t.Error("Expected Build not to apply Timeout")
+	}
+	res, err := client.Request(GET, "http://www.example.com").Timeout(time.Minute).RoundTrip()
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+}
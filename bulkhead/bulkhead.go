@@ -0,0 +1,190 @@
+package bulkhead
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrQueueFull - bulkhead wait queue is full
+var ErrQueueFull = errors.New("Bulkhead queue is full")
+
+// ErrQueueTimeout - waited in bulkhead queue longer than QueueTimeout
+var ErrQueueTimeout = errors.New("Bulkhead queue timeout")
+
+// Limit - bulkhead limits, zero MaxConcurrent means no limit
+type Limit struct {
+	// MaxConcurrent - max number of slots held at once
+	MaxConcurrent int
+
+	// MaxQueue - max number of callers waiting for a slot, zero
+	// means callers fail immediately once all slots are held
+	MaxQueue int
+
+	// QueueTimeout - max time caller waits for a slot, zero means
+	// wait until context is done
+	QueueTimeout time.Duration
+}
+
+// Stats - bulkhead usage snapshot
+type Stats struct {
+	InFlight, Queued int
+}
+
+// Bulkhead - semaphore with bounded wait queue
+type Bulkhead struct {
+	limit Limit
+	slots chan struct{}
+
+	mu     sync.Mutex
+	queued int
+}
+
+// New - returns Bulkhead for limit
+func New(l Limit) *Bulkhead {
+	return &Bulkhead{
+		limit: l,
+		slots: make(chan struct{}, l.MaxConcurrent),
+	}
+}
+
+// Acquire - takes slot, waiting in queue if none is free. Returned release
+// func must be called once slot is no longer used, it's safe to call it
+// multiple times.
+func (b *Bulkhead) Acquire(ctx context.Context) (release func(), err error) {
+	select {
+	case b.slots <- struct{}{}:
+		return b.releaseFunc(), nil
+	default:
+	}
+	b.mu.Lock()
+	if b.queued >= b.limit.MaxQueue {
+		b.mu.Unlock()
+		return nil, ErrQueueFull
+	}
+	b.queued++
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		b.queued--
+		b.mu.Unlock()
+	}()
+
+	var timeout <-chan time.Time
+	if b.limit.QueueTimeout > 0 {
+		timer := time.NewTimer(b.limit.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case b.slots <- struct{}{}:
+		return b.releaseFunc(), nil
+	case <-timeout:
+		return nil, ErrQueueTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// TryAcquire - takes slot if one is free without waiting
+func (b *Bulkhead) TryAcquire() (release func(), ok bool) {
+	select {
+	case b.slots <- struct{}{}:
+		return b.releaseFunc(), true
+	default:
+		return nil, false
+	}
+}
+
+func (b *Bulkhead) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() { <-b.slots })
+	}
+}
+
+// Stats - returns number of held slots and queued callers
+func (b *Bulkhead) Stats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return Stats{InFlight: len(b.slots), Queued: b.queued}
+}
+
+// Group - bulkheads keyed by host, created on first use and dropped once
+// no slot is held or waited for
+type Group struct {
+	limit Limit
+
+	mu        sync.Mutex
+	bulkheads map[string]*groupEntry
+}
+
+type groupEntry struct {
+	*Bulkhead
+	users int
+}
+
+// NewGroup - returns empty group, every key gets its own limit
+func NewGroup(l Limit) *Group {
+	return &Group{
+		limit:     l,
+		bulkheads: make(map[string]*groupEntry),
+	}
+}
+
+// Acquire - takes slot of key bulkhead, see Bulkhead.Acquire
+func (g *Group) Acquire(ctx context.Context, key string) (release func(), err error) {
+	g.mu.Lock()
+	e, ok := g.bulkheads[key]
+	if !ok {
+		e = &groupEntry{Bulkhead: New(g.limit)}
+		g.bulkheads[key] = e
+	}
+	e.users++
+	g.mu.Unlock()
+
+	r, err := e.Acquire(ctx)
+	if err != nil {
+		g.done(key, e)
+		return nil, err
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			r()
+			g.done(key, e)
+		})
+	}, nil
+}
+
+// done - drops bulkhead of key once it has no users
+func (g *Group) done(key string, e *groupEntry) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if e.users--; e.users == 0 {
+		delete(g.bulkheads, key)
+	}
+}
+
+// Len - number of keys with held or awaited slots
+func (g *Group) Len() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.bulkheads)
+}
+
+// Stats - returns stats of every key
+func (g *Group) Stats() map[string]Stats {
+	g.mu.Lock()
+	bulkheads := make(map[string]*Bulkhead, len(g.bulkheads))
+	for k, e := range g.bulkheads {
+		bulkheads[k] = e.Bulkhead
+	}
+	g.mu.Unlock()
+	stats := make(map[string]Stats, len(bulkheads))
+	for k, b := range bulkheads {
+		stats[k] = b.Stats()
+	}
+	return stats
+}
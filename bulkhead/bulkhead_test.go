@@ -0,0 +1,139 @@
+package bulkhead
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBulkhead(t *testing.T) {
+	b := New(Limit{MaxConcurrent: 1, MaxQueue: 1, QueueTimeout: 10 * time.Millisecond})
+	ctx := context.Background()
+
+	release, err := b.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queued := make(chan error)
+	go func() {
+		_, err := b.Acquire(ctx)
+		queued <- err
+	}()
+	for b.Stats().Queued != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := b.Acquire(ctx); err != ErrQueueFull {
+		t.Errorf("Expected ErrQueueFull got %v", err)
+	}
+	if err := <-queued; err != ErrQueueTimeout {
+		t.Errorf("Expected ErrQueueTimeout got %v", err)
+	}
+
+	release()
+	release()
+	if s := b.Stats(); s.InFlight != 0 || s.Queued != 0 {
+		t.Errorf("Expected empty bulkhead got %+v", s)
+	}
+}
+
+func TestGroupDropsIdleKeys(t *testing.T) {
+	g := NewGroup(Limit{MaxConcurrent: 1})
+	ctx := context.Background()
+	release, err := g.Acquire(ctx, "a.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Acquire(ctx, "a.example.com"); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected held slot to be kept per key got %v", err)
+	}
+	if n := g.Len(); n != 1 {
+		t.Errorf("Expected 1 key got %d", n)
+	}
+	release()
+	release()
+	if n := g.Len(); n != 0 {
+		t.Errorf("Expected released key to be dropped got %d keys", n)
+	}
+}
+
+func TestSetIdleConnections(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	a, b := httptest.NewServer(handler), httptest.NewServer(handler)
+	defer a.Close()
+	defer b.Close()
+
+	s := NewSet(&Config{Connections: Limit{MaxConcurrent: 1, QueueTimeout: time.Second}})
+	tr := &http.Transport{DialContext: s.DialContext((&net.Dialer{}).DialContext)}
+	defer tr.CloseIdleConnections()
+	s.CloseIdle = tr.CloseIdleConnections
+	client := &http.Client{Transport: s.Transport(tr)}
+
+	// keep-alive connection to every host stays idle once body is closed
+	for _, url := range []string{a.URL, b.URL, a.URL} {
+		res, err := client.Get(url)
+		if err != nil {
+			t.Fatalf("Expected idle connection to give its slot back got %v", err)
+		}
+		res.Body.Close()
+	}
+	if st := s.Stats(); st.Connections.InFlight != 1 {
+		t.Errorf("Expected single open connection got %d", st.Connections.InFlight)
+	}
+}
+
+func TestSetBusyConnections(t *testing.T) {
+	unblock := make(chan struct{})
+	a := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+	}))
+	b := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer a.Close()
+	defer b.Close()
+
+	s := NewSet(&Config{Connections: Limit{MaxConcurrent: 1, MaxQueue: 1, QueueTimeout: 50 * time.Millisecond}})
+	tr := &http.Transport{DialContext: s.DialContext((&net.Dialer{}).DialContext)}
+	defer tr.CloseIdleConnections()
+	var closed int32
+	s.CloseIdle = func() {
+		atomic.AddInt32(&closed, 1)
+		tr.CloseIdleConnections()
+	}
+	client := &http.Client{Transport: s.Transport(tr)}
+
+	done := make(chan error, 1)
+	go func() {
+		res, err := client.Get(a.URL)
+		if err == nil {
+			res.Body.Close()
+		}
+		done <- err
+	}()
+	for s.Stats().Connections.InFlight == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond) // request holds its connection
+	if _, err := client.Get(b.URL); !errors.Is(err, ErrQueueTimeout) {
+		t.Errorf("Expected queue timeout while connection is busy got %v", err)
+	}
+	if n := atomic.LoadInt32(&closed); n != 0 {
+		t.Errorf("Expected busy connection not to close idle pool got %d calls", n)
+	}
+	close(unblock)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	res, err := client.Get(b.URL)
+	if err != nil {
+		t.Fatalf("Expected idle connection to give its slot back got %v", err)
+	}
+	res.Body.Close()
+	if n := atomic.LoadInt32(&closed); n != 1 {
+		t.Errorf("Expected idle pool to be closed once got %d calls", n)
+	}
+}
@@ -0,0 +1,229 @@
+package bulkhead
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Config - client wide and per host limits of in-flight requests and
+// open connections
+type Config struct {
+	Requests, RequestsPerHost       Limit
+	Connections, ConnectionsPerHost Limit
+}
+
+// Set - request and connection bulkheads of a client
+type Set struct {
+	requests, connections         *Bulkhead
+	hostRequests, hostConnections *Group
+
+	// CloseIdle, if non-nil, is called when client wide connection limit
+	// is reached while some open connections are idle, before waiting for
+	// a slot, so idle keep-alive connections give their slots back, e.g.
+	// http.Transport.CloseIdleConnections. Connections are idle unless a
+	// request sent through Transport uses them, it closes idle connections
+	// of every host.
+	CloseIdle func()
+
+	// conns - open connections, busy - requests holding a connection
+	conns, busy int64
+}
+
+// SetStats - usage snapshot of Set, nil when limit is not set
+type SetStats struct {
+	Requests, Connections         *Stats
+	HostRequests, HostConnections map[string]Stats
+}
+
+// NewSet - returns bulkheads for limits set in config
+func NewSet(c *Config) *Set {
+	s := &Set{}
+	if c.Requests.MaxConcurrent > 0 {
+		s.requests = New(c.Requests)
+	}
+	if c.RequestsPerHost.MaxConcurrent > 0 {
+		s.hostRequests = NewGroup(c.RequestsPerHost)
+	}
+	if c.Connections.MaxConcurrent > 0 {
+		s.connections = New(c.Connections)
+	}
+	if c.ConnectionsPerHost.MaxConcurrent > 0 {
+		s.hostConnections = NewGroup(c.ConnectionsPerHost)
+	}
+	return s
+}
+
+// Stats - returns current in-flight and queue depth of every bulkhead
+func (s *Set) Stats() (stats SetStats) {
+	if s.requests != nil {
+		st := s.requests.Stats()
+		stats.Requests = &st
+	}
+	if s.connections != nil {
+		st := s.connections.Stats()
+		stats.Connections = &st
+	}
+	if s.hostRequests != nil {
+		stats.HostRequests = s.hostRequests.Stats()
+	}
+	if s.hostConnections != nil {
+		stats.HostConnections = s.hostConnections.Stats()
+	}
+	return stats
+}
+
+// acquire - takes client wide and host slot, host slot first so queued
+// requests of a slow host don't hold client wide slots. full, if non-nil,
+// is called before waiting for client wide slot.
+func acquire(ctx context.Context, all *Bulkhead, group *Group, host string, full func()) (func(), error) {
+	var releases []func()
+	release := func() {
+		for _, r := range releases {
+			r()
+		}
+	}
+	if group != nil {
+		r, err := group.Acquire(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		releases = append(releases, r)
+	}
+	if all != nil {
+		r, ok := all.TryAcquire()
+		var err error
+		if !ok {
+			if full != nil {
+				full()
+			}
+			r, err = all.Acquire(ctx)
+		}
+		if err != nil {
+			release()
+			return nil, err
+		}
+		releases = append(releases, r)
+	}
+	return release, nil
+}
+
+// closeIdle - calls CloseIdle when some open connections are idle
+func (s *Set) closeIdle() {
+	if s.CloseIdle != nil && atomic.LoadInt64(&s.conns) > atomic.LoadInt64(&s.busy) {
+		s.CloseIdle()
+	}
+}
+
+// Transport - returns RoundTripper limiting in-flight requests, slot is
+// held until response body is closed. With connection limits it also
+// tracks which connections are in use, see CloseIdle.
+func (s *Set) Transport(next http.RoundTripper) http.RoundTripper {
+	if s.requests == nil && s.hostRequests == nil && s.connections == nil {
+		return next
+	}
+	return &transport{set: s, next: next}
+}
+
+type transport struct {
+	set  *Set
+	next http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	release, err := acquire(req.Context(), t.set.requests, t.set.hostRequests, strings.ToLower(req.URL.Hostname()), nil)
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	if t.set.connections != nil {
+		req, release = t.set.trackConn(req, release)
+	}
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	res.Body = &releaseBody{ReadCloser: res.Body, release: release}
+	return res, nil
+}
+
+// trackConn - counts connection of req as busy from GotConn until
+// release, which is wrapped to give it back
+func (s *Set) trackConn(req *http.Request, release func()) (*http.Request, func()) {
+	var got int32
+	trace := &httptrace.ClientTrace{
+		GotConn: func(httptrace.GotConnInfo) {
+			if atomic.CompareAndSwapInt32(&got, 0, 1) {
+				atomic.AddInt64(&s.busy, 1)
+			}
+		},
+	}
+	var once sync.Once
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), func() {
+		once.Do(func() {
+			if !atomic.CompareAndSwapInt32(&got, 0, 2) {
+				atomic.AddInt64(&s.busy, -1)
+			}
+			release()
+		})
+	}
+}
+
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
+
+// DialContext - returns dial func limiting open connections, slot is held
+// until connection is closed
+func (s *Set) DialContext(next func(ctx context.Context, network, address string) (net.Conn, error)) func(ctx context.Context, network, address string) (net.Conn, error) {
+	if s.connections == nil && s.hostConnections == nil {
+		return next
+	}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			host = address
+		}
+		release, err := acquire(ctx, s.connections, s.hostConnections, strings.ToLower(host), s.closeIdle)
+		if err != nil {
+			return nil, err
+		}
+		conn, err := next(ctx, network, address)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		atomic.AddInt64(&s.conns, 1)
+		var once sync.Once
+		return &releaseConn{Conn: conn, release: func() {
+			once.Do(func() { atomic.AddInt64(&s.conns, -1) })
+			release()
+		}}, nil
+	}
+}
+
+type releaseConn struct {
+	net.Conn
+	release func()
+}
+
+func (c *releaseConn) Close() error {
+	err := c.Conn.Close()
+	c.release()
+	return err
+}
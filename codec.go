@@ -0,0 +1,215 @@
+package requestclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// ContentTypeXML - XML codec content type
+const ContentTypeXML = "application/xml; charset=utf-8"
+
+// ErrUnsupportedContentType - no codec registered for a content type
+var ErrUnsupportedContentType = errors.New("No codec registered for content type")
+
+// ErrUnsupportedValue - codec is unable to encode or decode value type
+var ErrUnsupportedValue = errors.New("Codec does not support value type")
+
+// Codec - encodes request bodies and decodes response bodies of
+// a single content type
+type Codec interface {
+	// ContentType returned codec sets on encoded requests
+	ContentType() string
+
+	Encode(v interface{}) ([]byte, error)
+	Decode(r io.Reader, v interface{}) error
+}
+
+var codecs = struct {
+	sync.RWMutex
+	m map[string]Codec
+}{m: make(map[string]Codec)}
+
+func init() {
+	RegisterCodec(JSONCodec{})
+	RegisterCodec(XMLCodec{}, "text/xml")
+	RegisterCodec(FormCodec{})
+	RegisterCodec(TextCodec{})
+}
+
+// RegisterCodec - registers codec for its content type and optional
+// additional content types, replacing previously registered codecs
+func RegisterCodec(c Codec, contentTypes ...string) {
+	codecs.Lock()
+	defer codecs.Unlock()
+	for _, ct := range append([]string{c.ContentType()}, contentTypes...) {
+		codecs.m[mediaType(ct)] = c
+	}
+}
+
+// CodecFor - returns codec registered for content type, structured syntax
+// suffixes such as application/problem+json fall back to application/json
+func CodecFor(contentType string) (Codec, bool) {
+	mt := mediaType(contentType)
+	codecs.RLock()
+	defer codecs.RUnlock()
+	if c, ok := codecs.m[mt]; ok {
+		return c, true
+	}
+	if i := strings.LastIndex(mt, "+"); i != -1 {
+		c, ok := codecs.m["application/"+mt[i+1:]]
+		return c, ok
+	}
+	return nil, false
+}
+
+// NewEncodedBody - encodes v with codec registered for content type
+func NewEncodedBody(contentType string, v interface{}) (*Body, error) {
+	c, ok := CodecFor(contentType)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedContentType, contentType)
+	}
+	data, err := c.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	return &Body{bytes.NewReader(data), c.ContentType(), int64(len(data))}, nil
+}
+
+// NewEncodedRequest - returns a new Request with v encoded by codec
+// registered for content type
+func (r *RequestClient) NewEncodedRequest(method string, u *url.URL, contentType string, v interface{}) (*http.Request, error) {
+	b, err := NewEncodedBody(contentType, v)
+	if err != nil {
+		return nil, err
+	}
+	return r.newBodyRequest(method, u, b), nil
+}
+
+func mediaType(contentType string) string {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(contentType))
+	}
+	return mt
+}
+
+// JSONCodec - application/json codec
+type JSONCodec struct{}
+
+// ContentType - implements Codec
+func (JSONCodec) ContentType() string { return ContentTypeJSON }
+
+// Encode - implements Codec
+func (JSONCodec) Encode(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+// Decode - implements Codec
+func (JSONCodec) Decode(r io.Reader, v interface{}) error { return json.NewDecoder(r).Decode(v) }
+
+// XMLCodec - application/xml codec
+type XMLCodec struct{}
+
+// ContentType - implements Codec
+func (XMLCodec) ContentType() string { return ContentTypeXML }
+
+// Encode - implements Codec
+func (XMLCodec) Encode(v interface{}) ([]byte, error) { return xml.Marshal(v) }
+
+// Decode - implements Codec
+func (XMLCodec) Decode(r io.Reader, v interface{}) error { return xml.NewDecoder(r).Decode(v) }
+
+// FormCodec - application/x-www-form-urlencoded codec, encodes url.Values,
+// map[string]string and map[string][]string, decodes into *url.Values and
+// *map[string]string
+type FormCodec struct{}
+
+// ContentType - implements Codec
+func (FormCodec) ContentType() string { return ContentTypeForm }
+
+// Encode - implements Codec
+func (FormCodec) Encode(v interface{}) ([]byte, error) {
+	switch t := v.(type) {
+	case url.Values:
+		return []byte(t.Encode()), nil
+	case map[string][]string:
+		return []byte(url.Values(t).Encode()), nil
+	case map[string]string:
+		values := make(url.Values, len(t))
+		for k, s := range t {
+			values.Set(k, s)
+		}
+		return []byte(values.Encode()), nil
+	}
+	return nil, fmt.Errorf("%w: %T", ErrUnsupportedValue, v)
+}
+
+// Decode - implements Codec
+func (FormCodec) Decode(r io.Reader, v interface{}) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return err
+	}
+	switch t := v.(type) {
+	case *url.Values:
+		*t = values
+	case *map[string]string:
+		m := make(map[string]string, len(values))
+		for k := range values {
+			m[k] = values.Get(k)
+		}
+		*t = m
+	default:
+		return fmt.Errorf("%w: %T", ErrUnsupportedValue, v)
+	}
+	return nil
+}
+
+// TextCodec - text/plain codec, encodes string, []byte and fmt.Stringer,
+// decodes into *string and *[]byte
+type TextCodec struct{}
+
+// ContentType - implements Codec
+func (TextCodec) ContentType() string { return ContentTypeText }
+
+// Encode - implements Codec
+func (TextCodec) Encode(v interface{}) ([]byte, error) {
+	switch t := v.(type) {
+	case string:
+		return []byte(t), nil
+	case []byte:
+		return t, nil
+	case fmt.Stringer:
+		return []byte(t.String()), nil
+	}
+	return nil, fmt.Errorf("%w: %T", ErrUnsupportedValue, v)
+}
+
+// Decode - implements Codec
+func (TextCodec) Decode(r io.Reader, v interface{}) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	switch t := v.(type) {
+	case *string:
+		*t = string(data)
+	case *[]byte:
+		*t = data
+	default:
+		return fmt.Errorf("%w: %T", ErrUnsupportedValue, v)
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package dialer
+
+import (
+	"math/rand"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultUnhealthyTime - how long failed address is tried last
+const DefaultUnhealthyTime = 30 * time.Second
+
+// AddrOrder - order resolved addresses of a host are tried in
+type AddrOrder int
+
+// Address orders, unhealthy addresses are always tried last
+const (
+	// AddrRandom - random order on every dial
+	AddrRandom AddrOrder = iota
+
+	// AddrRoundRobin - every dial starts with the next address
+	AddrRoundRobin
+
+	// AddrLeastRecentlyFailed - addresses that never failed first, then
+	// the ones that failed longest ago
+	AddrLeastRecentlyFailed
+)
+
+func (o AddrOrder) String() string {
+	switch o {
+	case AddrRandom:
+		return "random"
+	case AddrRoundRobin:
+		return "round-robin"
+	case AddrLeastRecentlyFailed:
+		return "least-recently-failed"
+	}
+	return "unknown"
+}
+
+// AttemptError - failed connection attempt to a single address
+type AttemptError struct {
+	IP  net.IP
+	Err error
+}
+
+func (e *AttemptError) Error() string {
+	if e.IP == nil {
+		return e.Err.Error()
+	}
+	return e.IP.String() + ": " + e.Err.Error()
+}
+
+func (e *AttemptError) Unwrap() error {
+	return e.Err
+}
+
+// DialError - every address tried for Address failed
+type DialError struct {
+	Address  string
+	Attempts []*AttemptError
+}
+
+func (e *DialError) Error() string {
+	msgs := make([]string, len(e.Attempts))
+	for i, a := range e.Attempts {
+		msgs[i] = a.Error()
+	}
+	return "Dial " + e.Address + ": " + strings.Join(msgs, "; ")
+}
+
+// Unwrap - errors of every attempt, so errors.Is and errors.As match
+// any of them
+func (e *DialError) Unwrap() []error {
+	errs := make([]error, len(e.Attempts))
+	for i, a := range e.Attempts {
+		errs[i] = a
+	}
+	return errs
+}
+
+// AddrHealth - connection failures per IP address and port, failed
+// address is unhealthy for UnhealthyTime unless a connection succeeds.
+// Expired failures and round-robin positions of hosts not dialed for
+// healthSweepInterval are dropped, so memory follows dialed addresses.
+type AddrHealth struct {
+	UnhealthyTime time.Duration
+
+	mu        sync.Mutex
+	failedAt  map[string]time.Time // by ip:port
+	next      map[string]*roundRobin
+	nextSweep time.Time
+}
+
+// healthSweepInterval - minimum time between sweeps of stale entries
+const healthSweepInterval = time.Minute
+
+// roundRobin - next position of a host and when it was last used
+type roundRobin struct {
+	n    int
+	used time.Time
+}
+
+// NewAddrHealth - returns AddrHealth with default unhealthy time
+func NewAddrHealth() *AddrHealth {
+	return &AddrHealth{
+		UnhealthyTime: DefaultUnhealthyTime,
+		failedAt:      make(map[string]time.Time),
+		next:          make(map[string]*roundRobin),
+	}
+}
+
+// Failed - marks ip unhealthy for port
+func (h *AddrHealth) Failed(ip net.IP, port string) {
+	now := time.Now()
+	h.mu.Lock()
+	h.failedAt[net.JoinHostPort(ip.String(), port)] = now
+	h.sweep(now)
+	h.mu.Unlock()
+}
+
+// Succeeded - marks ip healthy for port
+func (h *AddrHealth) Succeeded(ip net.IP, port string) {
+	h.mu.Lock()
+	delete(h.failedAt, net.JoinHostPort(ip.String(), port))
+	h.mu.Unlock()
+}
+
+// Healthy - whether ip did not fail for port within UnhealthyTime
+func (h *AddrHealth) Healthy(ip net.IP, port string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.healthy(net.JoinHostPort(ip.String(), port), time.Now())
+}
+
+func (h *AddrHealth) healthy(addr string, now time.Time) bool {
+	failedAt, ok := h.failedAt[addr]
+	if ok && now.Sub(failedAt) >= h.UnhealthyTime {
+		delete(h.failedAt, addr)
+		return true
+	}
+	return !ok
+}
+
+// sweep - drops expired failures and idle round-robin positions, at
+// most once per healthSweepInterval
+func (h *AddrHealth) sweep(now time.Time) {
+	if now.Before(h.nextSweep) {
+		return
+	}
+	h.nextSweep = now.Add(healthSweepInterval)
+	for addr := range h.failedAt {
+		h.healthy(addr, now)
+	}
+	for host, rr := range h.next {
+		if now.Sub(rr.used) >= healthSweepInterval {
+			delete(h.next, host)
+		}
+	}
+}
+
+// Order - returns copy of ips of host dialed on port in order, healthy
+// addresses first
+func (h *AddrHealth) Order(host, port string, ips []net.IP, order AddrOrder) []net.IP {
+	out := make([]net.IP, len(ips))
+	copy(out, ips)
+	now := time.Now()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sweep(now)
+	switch order {
+	case AddrRandom:
+		rand.Shuffle(len(out), func(i, j int) { out[i], out[j] = out[j], out[i] })
+	case AddrRoundRobin:
+		key := net.JoinHostPort(host, port)
+		rr, ok := h.next[key]
+		if !ok {
+			rr = &roundRobin{}
+			h.next[key] = rr
+		}
+		n := rr.n % len(out)
+		rr.n, rr.used = n+1, now
+		out = append(out[n:], out[:n]...)
+	case AddrLeastRecentlyFailed:
+		sort.SliceStable(out, func(i, j int) bool {
+			return h.failedAt[net.JoinHostPort(out[i].String(), port)].Before(h.failedAt[net.JoinHostPort(out[j].String(), port)])
+		})
+	}
+	h.healthyFirst(out, port, now)
+	return out
+}
+
+// HealthyFirst - moves unhealthy ips for port last in place, order is
+// kept otherwise, e.g. after interleaving families
+func (h *AddrHealth) HealthyFirst(ips []net.IP, port string) {
+	h.mu.Lock()
+	h.healthyFirst(ips, port, time.Now())
+	h.mu.Unlock()
+}
+
+func (h *AddrHealth) healthyFirst(ips []net.IP, port string, now time.Time) {
+	sort.SliceStable(ips, func(i, j int) bool {
+		return h.healthy(net.JoinHostPort(ips[i].String(), port), now) &&
+			!h.healthy(net.JoinHostPort(ips[j].String(), port), now)
+	})
+}
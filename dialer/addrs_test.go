@@ -0,0 +1,111 @@
+package dialer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestAddrHealthOrder(t *testing.T) {
+	a, b, c := net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2"), net.ParseIP("10.0.0.3")
+	ips := []net.IP{a, b, c}
+	h := NewAddrHealth()
+
+	if out := h.Order("example.com", "443", ips, AddrRoundRobin); !reflect.DeepEqual(out, []net.IP{a, b, c}) {
+		t.Errorf("Expected first round-robin order got %v", out)
+	}
+	if out := h.Order("example.com", "443", ips, AddrRoundRobin); !reflect.DeepEqual(out, []net.IP{b, c, a}) {
+		t.Errorf("Expected rotated round-robin order got %v", out)
+	}
+
+	h.Failed(a, "443")
+	h.Failed(b, "443")
+	h.Succeeded(b, "443")
+	if h.Healthy(a, "443") || !h.Healthy(b, "443") {
+		t.Error("Expected only failed address to be unhealthy")
+	}
+	if !h.Healthy(a, "80") {
+		t.Error("Expected failure to be kept per port")
+	}
+	if out := h.Order("example.com", "443", ips, AddrLeastRecentlyFailed); !reflect.DeepEqual(out, []net.IP{b, c, a}) {
+		t.Errorf("Expected failed address last got %v", out)
+	}
+	for i := 0; i < 10; i++ {
+		if out := h.Order("example.com", "443", ips, AddrRandom); !out[2].Equal(a) {
+			t.Fatalf("Expected unhealthy address last in random order got %v", out)
+		}
+	}
+}
+
+func TestAddrHealthSweep(t *testing.T) {
+	h := NewAddrHealth()
+	h.UnhealthyTime = time.Millisecond
+	for i := 0; i < 100; i++ {
+		ip := net.IPv4(10, 0, 0, byte(i))
+		h.Order("host"+strconv.Itoa(i)+".test", "443", []net.IP{ip}, AddrRoundRobin)
+		h.Failed(ip, "443")
+	}
+	time.Sleep(2 * time.Millisecond)
+	h.mu.Lock()
+	h.nextSweep = time.Time{}
+	for _, rr := range h.next {
+		rr.used = rr.used.Add(-healthSweepInterval)
+	}
+	h.mu.Unlock()
+	h.Failed(net.ParseIP("10.0.1.1"), "443")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.failedAt) != 1 || len(h.next) != 0 {
+		t.Errorf("Expected stale entries to be dropped got %d failures %d positions", len(h.failedAt), len(h.next))
+	}
+}
+
+func TestDualStackOrderKeepsUnhealthyLast(t *testing.T) {
+	a, c, e := net.ParseIP("10.0.0.1"), net.ParseIP("2001:db8::1"), net.ParseIP("2001:db8::2")
+	d := New()
+	d.DualStack = true
+	d.PreferredFamily = IPv4
+	d.AddrOrder = AddrLeastRecentlyFailed
+	d.Health.Failed(a, "443")
+	if out := d.order("example.com", "443", []net.IP{a, c, e}); !reflect.DeepEqual(out, []net.IP{c, e, a}) {
+		t.Errorf("Expected unhealthy preferred family address last got %v", out)
+	}
+}
+
+func TestDialerTriesEveryAddress(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+
+	d := New()
+	d.Order = []Source{SourceDNS}
+	d.AddrOrder = AddrRoundRobin
+	d.Cache = NewCache(LookupFunc(func(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+		return []net.IP{net.ParseIP("127.0.0.2"), net.ParseIP("127.0.0.1")}, time.Minute, nil
+	}))
+	conn, err := d.Dial("tcp", net.JoinHostPort("service.test", port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if d.Health.Healthy(net.ParseIP("127.0.0.2"), port) {
+		t.Error("Expected refused address to be unhealthy")
+	}
+
+	l.Close()
+	_, err = d.Dial("tcp", net.JoinHostPort("service.test", port))
+	var dialErr *DialError
+	if !errors.As(err, &dialErr) || len(dialErr.Attempts) != 2 {
+		t.Fatalf("Expected DialError listing both addresses got %v", err)
+	}
+	if !errors.Is(err, syscall.ECONNREFUSED) {
+		t.Errorf("Expected attempt errors to be wrapped got %v", err)
+	}
+}
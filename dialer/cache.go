@@ -0,0 +1,357 @@
+package dialer
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/linkosmos/godns"
+	"github.com/miekg/dns"
+)
+
+// Cache default values
+const (
+	DefaultCacheMinTTL       = 5 * time.Second
+	DefaultCacheMaxTTL       = 1 * time.Hour
+	DefaultCacheNegativeTTL  = 10 * time.Second
+	DefaultCacheMaxStale     = 30 * time.Second
+	DefaultCacheRefreshAhead = 0.8
+)
+
+// cacheShards - number of independently locked cache parts
+const cacheShards = 32
+
+// cachePurgeInterval - minimum time between sweeps of expired entries
+const cachePurgeInterval = 10 * time.Second
+
+// Cache - host addresses cache honoring record TTL. Entries are refreshed
+// in background once RefreshAhead of their TTL passed and are served
+// while refreshing, also up to MaxStale after expiry. NXDOMAIN, SERVFAIL
+// and empty answers are cached for NegativeTTL, other errors are not,
+// only NXDOMAIN replaces an entry still served stale. Cache is safe for concurrent use, concurrent lookups of the same host
+// share a single query. Cache is itself a Resolver, so it may wrap any
+// Resolver and be shared by many dialers.
+type Cache struct {
+	// MinTTL, MaxTTL - clamps of record TTL, zero MaxTTL means no limit
+	MinTTL, MaxTTL time.Duration
+
+	// NegativeTTL - how long NXDOMAIN, SERVFAIL and empty answers are cached
+	NegativeTTL time.Duration
+
+	// RefreshAhead - fraction of TTL after which entry is refreshed in
+	// background, 1 or more refreshes only stale entries
+	RefreshAhead float64
+
+	// MaxStale - how long expired entry may be served while refreshed in
+	// background, zero resolves expired entries synchronously
+	MaxStale time.Duration
+
+	resolver Resolver
+
+	shards [cacheShards]cacheShard
+
+	hits, misses, shared uint64
+}
+
+// CacheStats - cache counters since creation
+type CacheStats struct {
+	// Hits - lookups served from cache, including stale entries
+	Hits uint64
+
+	// Misses - lookups waiting for a query, Shared of them joined query
+	// of a concurrent lookup
+	Misses, Shared uint64
+
+	// Entries - number of cached hosts
+	Entries int
+}
+
+type cacheShard struct {
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	calls   map[string]*cacheCall
+	purgeAt time.Time
+}
+
+type cacheEntry struct {
+	ips                []net.IP
+	err                error
+	refreshAt, expires time.Time
+	refreshing         bool
+}
+
+// cacheCall - in-flight query shared by concurrent lookups
+type cacheCall struct {
+	done      chan struct{}
+	ips       []net.IP
+	err       error
+	cancelled bool
+}
+
+// NewCache - returns Cache with default values resolving through resolver
+func NewCache(resolver Resolver) *Cache {
+	c := &Cache{
+		MinTTL:       DefaultCacheMinTTL,
+		MaxTTL:       DefaultCacheMaxTTL,
+		NegativeTTL:  DefaultCacheNegativeTTL,
+		RefreshAhead: DefaultCacheRefreshAhead,
+		MaxStale:     DefaultCacheMaxStale,
+		resolver:     resolver,
+	}
+	for i := range c.shards {
+		c.shards[i].entries = make(map[string]*cacheEntry)
+		c.shards[i].calls = make(map[string]*cacheCall)
+	}
+	return c
+}
+
+// Lookup - returns IP's of host, hit is true when served from cache
+func (c *Cache) Lookup(ctx context.Context, host string) (ips []net.IP, hit bool, err error) {
+	now := time.Now()
+	key := cacheKey(ctx, host)
+	s := c.shard(key)
+	s.mu.Lock()
+	if e, ok := s.entries[key]; ok {
+		switch {
+		case now.Before(e.refreshAt):
+			s.mu.Unlock()
+			atomic.AddUint64(&c.hits, 1)
+			return e.ips, true, e.err
+		case e.err == nil && now.Before(e.expires.Add(c.MaxStale)):
+			if !e.refreshing {
+				e.refreshing = true
+				go c.refresh(withoutCancel(ctx), host)
+			}
+			s.mu.Unlock()
+			atomic.AddUint64(&c.hits, 1)
+			return e.ips, true, nil
+		}
+	}
+	s.mu.Unlock()
+	atomic.AddUint64(&c.misses, 1)
+	ips, err = c.resolveShared(ctx, host)
+	return ips, false, err
+}
+
+// LookupAddrs - implements Resolver, ttl is the remaining entry TTL
+func (c *Cache) LookupAddrs(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+	ips, _, err := c.Lookup(ctx, host)
+	if err != nil {
+		return nil, 0, err
+	}
+	key := cacheKey(ctx, host)
+	s := c.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ttl time.Duration
+	if e, ok := s.entries[key]; ok {
+		if ttl = time.Until(e.expires); ttl < 0 {
+			ttl = 0
+		}
+	}
+	return ips, ttl, nil
+}
+
+// Cached - whether host has unexpired entry, of either lookup family
+func (c *Cache) Cached(host string) bool {
+	now := time.Now()
+	for _, key := range []string{host, host + cacheIPv6Suffix} {
+		s := c.shard(key)
+		s.mu.Lock()
+		e, ok := s.entries[key]
+		s.mu.Unlock()
+		if ok && now.Before(e.expires) {
+			return true
+		}
+	}
+	return false
+}
+
+// Delete - removes entries of host
+func (c *Cache) Delete(host string) {
+	for _, key := range []string{host, host + cacheIPv6Suffix} {
+		s := c.shard(key)
+		s.mu.Lock()
+		delete(s.entries, key)
+		s.mu.Unlock()
+	}
+}
+
+// Purge - removes entries no longer servable
+func (c *Cache) Purge() {
+	now := time.Now()
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.mu.Lock()
+		c.purge(s, now)
+		s.mu.Unlock()
+	}
+}
+
+// Stats - hit and miss counters and number of entries
+func (c *Cache) Stats() CacheStats {
+	st := CacheStats{
+		Hits:   atomic.LoadUint64(&c.hits),
+		Misses: atomic.LoadUint64(&c.misses),
+		Shared: atomic.LoadUint64(&c.shared),
+	}
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.mu.Lock()
+		st.Entries += len(s.entries)
+		s.mu.Unlock()
+	}
+	return st
+}
+
+func (c *Cache) shard(host string) *cacheShard {
+	h := fnv.New32a()
+	h.Write([]byte(host))
+	return &c.shards[h.Sum32()%cacheShards]
+}
+
+// cacheIPv6Suffix - appended to host in keys of lookups with AAAA records
+const cacheIPv6Suffix = "/ip6"
+
+// cacheKey - entries of lookups with and without AAAA records of host
+// are kept apart, see WithIPv6
+func cacheKey(ctx context.Context, host string) string {
+	if ipv6, ok := contextIPv6(ctx); ok && ipv6 {
+		return host + cacheIPv6Suffix
+	}
+	return host
+}
+
+// withoutCancel - background ctx keeping lookup family of ctx
+func withoutCancel(ctx context.Context) context.Context {
+	if ipv6, ok := contextIPv6(ctx); ok {
+		return WithIPv6(context.Background(), ipv6)
+	}
+	return context.Background()
+}
+
+func (c *Cache) refresh(ctx context.Context, host string) {
+	if _, err := c.resolveShared(ctx, host); err != nil {
+		key := cacheKey(ctx, host)
+		s := c.shard(key)
+		s.mu.Lock()
+		if e, ok := s.entries[key]; ok {
+			e.refreshing = false
+		}
+		s.mu.Unlock()
+	}
+}
+
+// resolveShared - resolves host once for all concurrent callers, callers
+// stop waiting when their ctx is done and query again when the query was
+// cancelled by ctx of the caller that started it
+func (c *Cache) resolveShared(ctx context.Context, host string) ([]net.IP, error) {
+	key := cacheKey(ctx, host)
+	s := c.shard(key)
+	for {
+		s.mu.Lock()
+		if call, ok := s.calls[key]; ok {
+			s.mu.Unlock()
+			atomic.AddUint64(&c.shared, 1)
+			select {
+			case <-call.done:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			if call.cancelled && ctx.Err() == nil {
+				continue
+			}
+			return call.ips, call.err
+		}
+		call := &cacheCall{done: make(chan struct{})}
+		s.calls[key] = call
+		s.mu.Unlock()
+
+		call.ips, call.err = c.resolve(ctx, host)
+		call.cancelled = ctx.Err() != nil
+		s.mu.Lock()
+		delete(s.calls, key)
+		s.mu.Unlock()
+		close(call.done)
+		return call.ips, call.err
+	}
+}
+
+// resolve - looks up host and stores result, errors other than NXDOMAIN
+// leave existing entry untouched while it may be served stale
+func (c *Cache) resolve(ctx context.Context, host string) ([]net.IP, error) {
+	ips, ttl, err := c.resolver.LookupAddrs(ctx, host)
+	if err == nil && len(ips) == 0 {
+		err = godns.ErrEmptyIPS
+	}
+	now := time.Now()
+	e := &cacheEntry{ips: ips, err: err}
+	switch {
+	case err == nil:
+		if ttl < c.MinTTL {
+			ttl = c.MinTTL
+		}
+		if c.MaxTTL > 0 && ttl > c.MaxTTL {
+			ttl = c.MaxTTL
+		}
+		e.expires = now.Add(ttl)
+		e.refreshAt = e.expires
+		if c.RefreshAhead > 0 && c.RefreshAhead < 1 {
+			e.refreshAt = now.Add(time.Duration(float64(ttl) * c.RefreshAhead))
+		}
+	case NegativeError(err) && c.NegativeTTL > 0:
+		e.ips = nil
+		e.expires = now.Add(c.NegativeTTL)
+		e.refreshAt = e.expires
+	default:
+		return nil, err
+	}
+	key := cacheKey(ctx, host)
+	s := c.shard(key)
+	s.mu.Lock()
+	if old, ok := s.entries[key]; ok && e.err != nil && !nameError(e.err) &&
+		old.err == nil && now.Before(old.expires.Add(c.MaxStale)) {
+		// SERVFAIL and empty answers are likely transient
+		s.mu.Unlock()
+		return nil, err
+	}
+	s.entries[key] = e
+	if now.After(s.purgeAt) {
+		c.purge(s, now)
+		interval := c.MinTTL + c.MaxStale
+		if interval < cachePurgeInterval {
+			interval = cachePurgeInterval
+		}
+		s.purgeAt = now.Add(interval)
+	}
+	s.mu.Unlock()
+	return e.ips, e.err
+}
+
+func (c *Cache) purge(s *cacheShard, now time.Time) {
+	for host, e := range s.entries {
+		if !e.refreshing && now.After(e.expires.Add(c.MaxStale)) {
+			delete(s.entries, host)
+		}
+	}
+}
+
+// nameError - whether err is NXDOMAIN
+func nameError(err error) bool {
+	var rcodeErr *RcodeError
+	return errors.As(err, &rcodeErr) && rcodeErr.Rcode == dns.RcodeNameError
+}
+
+// NegativeError - whether err is NXDOMAIN, SERVFAIL or empty answer
+func NegativeError(err error) bool {
+	if errors.Is(err, godns.ErrEmptyIPS) {
+		return true
+	}
+	var rcodeErr *RcodeError
+	return errors.As(err, &rcodeErr) &&
+		(rcodeErr.Rcode == dns.RcodeNameError || rcodeErr.Rcode == dns.RcodeServerFailure)
+}
@@ -0,0 +1,232 @@
+package dialer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func TestCacheTTL(t *testing.T) {
+	var calls int32
+	c := NewCache(LookupFunc(func(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+		atomic.AddInt32(&calls, 1)
+		return []net.IP{net.IPv4(10, 0, 0, 1)}, 0, nil
+	}))
+	c.MinTTL = 50 * time.Millisecond
+	c.MaxStale = 0
+	ctx := context.Background()
+
+	if _, hit, err := c.Lookup(ctx, "example.com"); err != nil || hit {
+		t.Fatalf("Expected miss got hit %v err %v", hit, err)
+	}
+	if _, hit, _ := c.Lookup(ctx, "example.com"); !hit {
+		t.Error("Expected hit within TTL clamped to MinTTL")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if _, hit, _ := c.Lookup(ctx, "example.com"); hit {
+		t.Error("Expected expired entry to be resolved again")
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("Expected 2 lookups got %d", n)
+	}
+}
+
+func TestCacheStaleWhileRevalidate(t *testing.T) {
+	refreshed := make(chan struct{}, 1)
+	var calls int32
+	c := NewCache(LookupFunc(func(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+		if atomic.AddInt32(&calls, 1) > 1 {
+			refreshed <- struct{}{}
+			return []net.IP{net.IPv4(10, 0, 0, 2)}, time.Second, nil
+		}
+		return []net.IP{net.IPv4(10, 0, 0, 1)}, 0, nil
+	}))
+	c.MinTTL = 10 * time.Millisecond
+	ctx := context.Background()
+
+	c.Lookup(ctx, "example.com")
+	time.Sleep(20 * time.Millisecond)
+	ips, hit, err := c.Lookup(ctx, "example.com")
+	if err != nil || !hit || !ips[0].Equal(net.IPv4(10, 0, 0, 1)) {
+		t.Fatalf("Expected stale entry got %v hit %v err %v", ips, hit, err)
+	}
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatal("Expected background refresh")
+	}
+	for i := 0; i < 100; i++ {
+		if ips, _, _ = c.Lookup(ctx, "example.com"); ips[0].Equal(net.IPv4(10, 0, 0, 2)) {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Errorf("Expected refreshed entry got %v", ips)
+}
+
+func TestCacheNegative(t *testing.T) {
+	var calls int32
+	rcode := dns.RcodeNameError
+	c := NewCache(LookupFunc(func(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, 0, &RcodeError{Name: host, Rcode: rcode}
+	}))
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, _, err := c.Lookup(ctx, "missing.example.com"); !NegativeError(err) {
+			t.Fatalf("Expected NXDOMAIN got %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("Expected NXDOMAIN to be cached got %d lookups", n)
+	}
+
+	rcode = dns.RcodeRefused
+	c.Lookup(ctx, "refused.example.com")
+	c.Lookup(ctx, "refused.example.com")
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Errorf("Expected REFUSED not to be cached got %d lookups", n)
+	}
+}
+
+func TestCacheKeepsStaleOnError(t *testing.T) {
+	var calls int32
+	c := NewCache(LookupFunc(func(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+		if atomic.AddInt32(&calls, 1) > 1 {
+			return nil, 0, errors.New("network down")
+		}
+		return []net.IP{net.IPv4(10, 0, 0, 1)}, 0, nil
+	}))
+	c.MinTTL = 10 * time.Millisecond
+	ctx := context.Background()
+
+	c.Lookup(ctx, "example.com")
+	time.Sleep(20 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		if _, _, err := c.Lookup(ctx, "example.com"); err != nil {
+			t.Fatalf("Expected stale entry while refresh fails got %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCacheKeepsStaleOnServfail(t *testing.T) {
+	for _, failure := range []error{&RcodeError{Rcode: dns.RcodeServerFailure}, nil} {
+		var calls int32
+		c := NewCache(LookupFunc(func(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+			if atomic.AddInt32(&calls, 1) > 1 {
+				return nil, 0, failure // SERVFAIL or empty answer
+			}
+			return []net.IP{net.IPv4(10, 0, 0, 1)}, 0, nil
+		}))
+		c.MinTTL = 10 * time.Millisecond
+		ctx := context.Background()
+
+		c.Lookup(ctx, "example.com")
+		time.Sleep(20 * time.Millisecond)
+		for i := 0; i < 3; i++ {
+			if ips, _, err := c.Lookup(ctx, "example.com"); err != nil || len(ips) != 1 {
+				t.Fatalf("%v: expected stale entry while refresh fails got %v %v", failure, ips, err)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		if n := atomic.LoadInt32(&calls); n < 2 {
+			t.Errorf("%v: expected background refresh got %d lookups", failure, n)
+		}
+	}
+}
+
+func TestCacheSingleflight(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	c := NewCache(LookupFunc(func(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return []net.IP{net.IPv4(10, 0, 0, 1)}, time.Minute, nil
+	}))
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			if _, _, err := c.Lookup(ctx, host); err != nil {
+				t.Error(err)
+			}
+		}([]string{"a.example.com", "b.example.com"}[i%2])
+	}
+	for atomic.LoadUint64(&c.shared) < 18 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	c.Lookup(ctx, "a.example.com")
+
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("Expected single query per host got %d", n)
+	}
+	st := c.Stats()
+	if st.Hits != 1 || st.Misses != 20 || st.Shared != 18 || st.Entries != 2 {
+		t.Errorf("Unexpected stats %+v", st)
+	}
+}
+
+func TestCacheSharedCancel(t *testing.T) {
+	started := make(chan struct{})
+	var calls int32
+	c := NewCache(LookupFunc(func(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+			<-ctx.Done()
+			return nil, 0, ctx.Err()
+		}
+		return []net.IP{net.IPv4(10, 0, 0, 1)}, time.Minute, nil
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	go c.Lookup(ctx, "example.com")
+	<-started
+
+	done := make(chan error)
+	go func() {
+		_, _, err := c.Lookup(context.Background(), "example.com")
+		done <- err
+	}()
+	for atomic.LoadUint64(&c.shared) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Expected waiter to query again after leader cancelled got %v", err)
+	}
+}
+
+func TestCacheDualStackLookups(t *testing.T) {
+	d := New()
+	d.Order = []Source{SourceDNS}
+	d.Cache = NewCache(LookupFunc(func(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+		if lookupIPv6(ctx, false) {
+			return []net.IP{net.IPv6loopback, net.IPv4(127, 0, 0, 1)}, time.Minute, nil
+		}
+		return []net.IP{net.IPv4(127, 0, 0, 1)}, time.Minute, nil
+	}))
+	ctx := context.Background()
+	if ips, _, err := d.lookupHost(ctx, "api.test"); err != nil || len(ips) != 1 {
+		t.Errorf("Expected IPv4 only lookup got %v %v", ips, err)
+	}
+	d.DualStack = true
+	if ips, _, err := d.lookupHost(ctx, "api.test"); err != nil || len(ips) != 2 {
+		t.Errorf("Expected DualStack lookup with AAAA records got %v %v", ips, err)
+	}
+	d.Cache.Delete("api.test")
+	if d.Cache.Cached("api.test") {
+		t.Error("Expected entries of both families to be deleted")
+	}
+}
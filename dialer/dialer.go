@@ -1,45 +1,248 @@
 package dialer
 
 import (
+	"context"
 	"net"
+	"net/http/httptrace"
+	"time"
 
-	"github.com/Sirupsen/logrus"
-	"github.com/linkosmos/godns"
+	"github.com/linkosmos/requestclient/logger"
 )
 
 // Dialer -
 type Dialer struct {
 	*net.Dialer
 
-	AddrsPool *godns.Pool
+	// Budget - time limit of a single dial, including lookup and every
+	// connection attempt, zero means no limit. Timeout of net.Dialer
+	// limits each connection attempt, its Deadline every dial.
+	Budget time.Duration
+
+	// HostResolver, if non-nil, resolves every host instead of Order
+	// sources, it may be shared by many dialers
+	HostResolver Resolver
+
+	// DNS - name servers resolver of SourceDNS
+	DNS *DNSResolver
+
+	// Cache - DNS results, honoring record TTL
+	Cache *Cache
+
+	// AddrOrder - order resolved addresses are tried in, addresses that
+	// failed recently are tried last, see Health
+	AddrOrder AddrOrder
+	Health    *AddrHealth
+
+	// PreferredFamily - family of the first connection attempt with
+	// DualStack, later attempts alternate families
+	PreferredFamily Family
+
+	// HostOverrides - fixed addresses of host:port, consulted before any
+	// lookup. Keys are lower case.
+	HostOverrides map[string][]net.IP
+
+	// ConnectTo - authority connections are made to instead of the dialed
+	// one, by host:port or by host, value's empty host or port keeps the
+	// dialed one. HTTP Host header and TLS server name are not affected.
+	// Keys are lower case.
+	ConnectTo map[string]string
+
+	// Hosts - hosts file consulted before name servers, nil skips it
+	Hosts *Hosts
+
+	// SRVResolver - resolves SRV records of service names, nil means
+	// uncached DNS
+	SRVResolver SRVResolver
+
+	// Order - address sources tried in order until one has addresses,
+	// nil means DefaultOrder. IP literals are never looked up.
+	Order []Source
+
+	// Logger - receives resolve and fallback events, no-op by default
+	Logger logger.Logger
 }
 
-// New - initalize dial.Dialer wrapper
+// New - initalize dial.Dialer wrapper, resolving through hosts file,
+// cached name servers of DefaultResolvConf and system resolver, service
+// names through cached name servers
 func New() *Dialer {
-	return &Dialer{
-		Dialer:    &net.Dialer{},
-		AddrsPool: godns.New(),
+	d := &Dialer{
+		Dialer: &net.Dialer{},
+		DNS:    NewDNSResolver(),
+		Hosts:  NewHosts(DefaultHosts),
+		Health: NewAddrHealth(),
+		Logger: logger.Nop{},
 	}
+	d.Cache = NewCache(d.DNS)
+	d.SRVResolver = NewSRVCache(d.DNS)
+	return d
+}
+
+// SetResolvConf - uses name servers, search domains and ndots of conf
+// for DNS.
+//
+// Deprecated: use DNS.SetResolvConf.
+func (d *Dialer) SetResolvConf(conf *ResolvConf) {
+	d.DNS.SetResolvConf(conf)
 }
 
 // Dial - lightweight version of dialer.Dial, this has cached
 // dns hostport and shorter TCP connection setup
 func (d *Dialer) Dial(network, address string) (net.Conn, error) {
-	tcpAddr, err := d.AddrsPool.Get(address)
+	return d.DialContext(context.Background(), network, address)
+}
+
+// DialContext - same as Dial, DNS lookup and TCP dial are aborted
+// when ctx is done. Resolved addresses are tried one by one in AddrOrder
+// until one connects, with DualStack IPv6 and IPv4 addresses are raced as
+// by RFC 8305 Happy Eyeballs. Service names, e.g. _api._tcp.internal, are
+// resolved to SRV targets tried in RFC 2782 order, dialed port is ignored.
+// Returns *DialError when all attempts fail.
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	if d.Budget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.Budget)
+		defer cancel()
+	}
+	if !d.Deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, d.Deadline)
+		defer cancel()
+	}
+	target := d.connectTo(address)
+	if host, _, err := net.SplitHostPort(target); err == nil && isService(host) {
+		return d.dialService(ctx, network, address, host)
+	}
+	return d.dialAddress(ctx, network, address, target)
+}
+
+// dialAddress - resolves target host and connects to its addresses,
+// address is the dialed one target is connected to instead of
+func (d *Dialer) dialAddress(ctx context.Context, network, address, target string) (net.Conn, error) {
+	ips, err := d.lookup(ctx, target)
 	if err != nil {
-		logrus.Warningf("Failed to resolve: %s, fallback dialer.Dial", address)
-		return d.Dialer.Dial(network, address)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		d.Logger.Warn("Failed to resolve", logger.Fields{
+			"address": address,
+			"error":   err,
+		})
+		return nil, err
+	}
+	ips = filterNetwork(network, ips)
+	if len(ips) == 0 {
+		return nil, &net.AddrError{Err: "no suitable address found", Addr: address}
+	}
+	host, port, _ := net.SplitHostPort(target)
+	ips = d.order(host, port, ips)
+	var conn net.Conn
+	var attempts []*AttemptError
+	if d.DualStack && len(ips) > 1 {
+		conn, attempts = d.dialParallel(ctx, network, port, ips)
+	} else {
+		conn, attempts = d.dialSerial(ctx, network, port, ips)
+	}
+	if conn != nil {
+		return conn, nil
 	}
-	c, err := net.DialTCP(network, nil, tcpAddr)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	err = &DialError{Address: address, Attempts: attempts}
+	d.Logger.Warn("Failed to connect", logger.Fields{
+		"address": address,
+		"error":   err,
+	})
+	return nil, err
+}
+
+// order - ips of host in the order they are tried, with DualStack
+// families alternate, unhealthy addresses are last either way
+func (d *Dialer) order(host, port string, ips []net.IP) []net.IP {
+	ips = d.Health.Order(host, port, ips, d.AddrOrder)
+	if d.DualStack && len(ips) > 1 {
+		ips = interleave(ips, d.PreferredFamily)
+		d.Health.HealthyFirst(ips, port)
+	}
+	return ips
+}
+
+// dialSerial - tries ips one by one until one connects
+func (d *Dialer) dialSerial(ctx context.Context, network, port string, ips []net.IP) (net.Conn, []*AttemptError) {
+	var attempts []*AttemptError
+	for _, ip := range ips {
+		conn, err := d.attempt(ctx, network, ip, port)
+		if err == nil {
+			return conn, nil
+		}
+		attempts = append(attempts, &AttemptError{IP: ip, Err: err})
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, attempts
+}
+
+// attempt - connects to ip, updating its health unless ctx is done
+func (d *Dialer) attempt(ctx context.Context, network string, ip net.IP, port string) (net.Conn, error) {
+	conn, err := d.dialOne(ctx, network, net.JoinHostPort(ip.String(), port))
+	switch {
+	case err == nil:
+		d.Health.Succeeded(ip, port)
+	case ctx.Err() == nil:
+		d.Health.Failed(ip, port)
+	}
+	return conn, err
+}
+
+// dialOne - connects to a single IP address within Timeout
+func (d *Dialer) dialOne(ctx context.Context, network, address string) (net.Conn, error) {
+	nd := net.Dialer{
+		Timeout:   d.Timeout,
+		LocalAddr: d.LocalAddr,
+		Control:   d.Control,
+	}
+	conn, err := nd.DialContext(ctx, network, address)
 	if err != nil {
-		logrus.Warningf("Failed to setup DialTCP: %s, fallback dialer.Dial", err)
-		return d.Dialer.Dial(network, address)
+		return nil, err
 	}
-	if d.KeepAlive != 0 {
+	if c, ok := conn.(*net.TCPConn); ok && d.KeepAlive != 0 {
 		c.SetKeepAlive(true)
 		c.SetKeepAlivePeriod(d.KeepAlive)
 		c.SetLinger(0)
 		c.SetNoDelay(true)
 	}
-	return c, err
+	return conn, nil
+}
+
+// lookup - resolves address host, IP literals and HostOverrides skip
+// lookup and its httptrace and dialer trace hooks
+func (d *Dialer) lookup(ctx context.Context, address string) ([]net.IP, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+	if ips := d.hostOverride(address); len(ips) > 0 {
+		return ips, nil
+	}
+	trace := httptrace.ContextClientTrace(ctx)
+	if trace != nil && trace.DNSStart != nil {
+		trace.DNSStart(httptrace.DNSStartInfo{Host: host})
+	}
+	ips, hit, err := d.lookupHost(ctx, host)
+	if t := ContextTrace(ctx); t != nil && t.DNSCacheHit != nil {
+		t.DNSCacheHit(host, hit)
+	}
+	if trace != nil && trace.DNSDone != nil {
+		info := httptrace.DNSDoneInfo{Err: err}
+		for _, ip := range ips {
+			info.Addrs = append(info.Addrs, net.IPAddr{IP: ip})
+		}
+		trace.DNSDone(info)
+	}
+	return ips, err
 }
@@ -0,0 +1,57 @@
+package dialer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestDialerBudget(t *testing.T) {
+	d := New()
+	d.Order = []Source{SourceDNS}
+	d.Budget = 50 * time.Millisecond
+	d.Cache = NewCache(LookupFunc(func(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+		<-ctx.Done()
+		return nil, 0, ctx.Err()
+	}))
+	start := time.Now()
+	if _, err := d.Dial("tcp", "slow.test:80"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected budget to expire got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected dial to end within budget took %s", elapsed)
+	}
+}
+
+func TestConnectTo(t *testing.T) {
+	d := &Dialer{ConnectTo: map[string]string{
+		"api.test:443": "canary.test:8443",
+		"cdn.test":     "origin.test",
+		"web.test:80":  ":8080",
+	}}
+	for address, expected := range map[string]string{
+		"API.test:443": "canary.test:8443",
+		"api.test:80":  "api.test:80",
+		"cdn.test:443": "origin.test:443",
+		"web.test:80":  "web.test:8080",
+	} {
+		if target := d.connectTo(address); target != expected {
+			t.Errorf("%s: expected %s got %s", address, expected, target)
+		}
+	}
+}
+
+func TestDialUDP(t *testing.T) {
+	d := New()
+	d.KeepAlive = time.Second
+	conn, err := d.Dial("udp", "127.0.0.1:9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := conn.(*net.UDPConn); !ok {
+		t.Errorf("Expected UDP connection got %T", conn)
+	}
+	conn.Close()
+}
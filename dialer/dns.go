@@ -0,0 +1,289 @@
+package dialer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/linkosmos/godns"
+	"github.com/linkosmos/requestclient/logger"
+	"github.com/miekg/dns"
+)
+
+// DNS default values
+const (
+	DefaultDNSTimeout     = 5 * time.Second
+	DefaultDNSStepTimeout = 2 * time.Second
+	DefaultDNSRetryWait   = 2 * time.Second
+)
+
+// ErrTruncated - name server truncated reply sent over TCP
+var ErrTruncated = errors.New("DNS reply truncated over TCP")
+
+// DNSResolver - Resolver querying name servers over TCP, with failover
+// between name servers and resolv.conf search domains
+type DNSResolver struct {
+	// NameServers - queried in order, unhealthy servers are skipped
+	NameServers *NameServers
+
+	// Timeout - limit of retrying temporary errors of a name server,
+	// StepTimeout - read and write timeout of each exchange, RetryWait -
+	// wait before first retry, doubled after each retry
+	Timeout, StepTimeout, RetryWait time.Duration
+
+	// Search, Ndots - search domains tried for names with fewer than
+	// Ndots dots, as with resolv.conf
+	Search []string
+	Ndots  int
+
+	// IPv6 - query AAAA records along with A records, unless lookup ctx
+	// says otherwise, see WithIPv6
+	IPv6 bool
+
+	// Logger - receives resolve and failover events, no-op by default
+	Logger logger.Logger
+}
+
+// NewDNSResolver - name servers and search domains are read from
+// DefaultResolvConf, godns.DefaultNameServer is used when it's missing
+func NewDNSResolver() *DNSResolver {
+	r := &DNSResolver{
+		NameServers: NewNameServers(godns.DefaultNameServer),
+		Timeout:     DefaultDNSTimeout,
+		StepTimeout: DefaultDNSStepTimeout,
+		RetryWait:   DefaultDNSRetryWait,
+		Ndots:       1,
+		Logger:      logger.Nop{},
+	}
+	if conf, err := LoadResolvConf(DefaultResolvConf); err == nil {
+		r.SetResolvConf(conf)
+	}
+	return r
+}
+
+// SetResolvConf - uses name servers, search domains and ndots of conf,
+// name servers are kept when conf has none
+func (r *DNSResolver) SetResolvConf(conf *ResolvConf) {
+	if len(conf.Nameservers) > 0 {
+		r.NameServers = NewNameServers(conf.Nameservers...)
+	}
+	r.Search = conf.Search
+	r.Ndots = conf.Ndots
+}
+
+// LookupAddrs - queries name servers for A records of host, also AAAA
+// records with IPv6, trying search domains while names don't exist
+func (r *DNSResolver) LookupAddrs(ctx context.Context, host string) (ips []net.IP, ttl time.Duration, err error) {
+	for _, name := range r.searchNames(host) {
+		if lookupIPv6(ctx, r.IPv6) {
+			ips, ttl, err = queryDual(ctx, name, r.queryAddrs)
+		} else {
+			ips, ttl, err = r.queryAddrs(ctx, name, dns.TypeA)
+		}
+		if err == nil && len(ips) == 0 {
+			err = godns.ErrEmptyIPS
+		}
+		if err == nil || !NegativeError(err) {
+			break
+		}
+	}
+	return ips, ttl, err
+}
+
+type ipv6Key struct{}
+
+// WithIPv6 - returns ctx of lookups querying AAAA records along with A
+// records only when ipv6 is true, overriding IPv6 of DNSResolver and
+// DoHResolver. Dialer sets it from DualStack on every lookup.
+func WithIPv6(ctx context.Context, ipv6 bool) context.Context {
+	return context.WithValue(ctx, ipv6Key{}, ipv6)
+}
+
+// contextIPv6 - IPv6 set on ctx with WithIPv6, ok is false if not set
+func contextIPv6(ctx context.Context) (ipv6, ok bool) {
+	ipv6, ok = ctx.Value(ipv6Key{}).(bool)
+	return ipv6, ok
+}
+
+// lookupIPv6 - whether lookup with ctx queries AAAA records, resolver
+// default is used when ctx has none
+func lookupIPv6(ctx context.Context, ipv6 bool) bool {
+	if v, ok := contextIPv6(ctx); ok {
+		return v
+	}
+	return ipv6
+}
+
+// queryDual - queries A and AAAA records of name concurrently, either
+// family having addresses is success, ttl is the lower one
+func queryDual(ctx context.Context, name string, query func(ctx context.Context, name string, qtype uint16) ([]net.IP, time.Duration, error)) ([]net.IP, time.Duration, error) {
+	type answer struct {
+		ips []net.IP
+		ttl time.Duration
+		err error
+	}
+	aaaa := make(chan answer, 1)
+	go func() {
+		ips, ttl, err := query(ctx, name, dns.TypeAAAA)
+		aaaa <- answer{ips, ttl, err}
+	}()
+	ips, ttl, err := query(ctx, name, dns.TypeA)
+	a6 := <-aaaa
+	if len(a6.ips) == 0 {
+		return ips, ttl, err
+	}
+	if len(ips) == 0 {
+		return a6.ips, a6.ttl, nil
+	}
+	if a6.ttl < ttl {
+		ttl = a6.ttl
+	}
+	return append(ips, a6.ips...), ttl, nil
+}
+
+// queryAddrs - addresses of qtype records of name
+func (r *DNSResolver) queryAddrs(ctx context.Context, name string, qtype uint16) ([]net.IP, time.Duration, error) {
+	reply, err := r.exchange(ctx, name, qtype)
+	if err != nil {
+		return nil, 0, err
+	}
+	ips, ttl := answerAddrs(reply)
+	return ips, ttl, nil
+}
+
+// exchange - queries name servers in order until one answers, reply codes
+// other than NXDOMAIN fail over to the next server, ErrNoSource without
+// name servers
+func (r *DNSResolver) exchange(ctx context.Context, name string, qtype uint16) (reply *dns.Msg, err error) {
+	var servers []string
+	if r.NameServers != nil {
+		servers = r.NameServers.Order()
+	}
+	err = ErrNoSource // no name servers
+	for _, server := range servers {
+		var duration time.Duration
+		reply, duration, err = r.exchangeServer(ctx, name, server, qtype)
+		var rcodeErr *RcodeError
+		answered := err == nil || errors.As(err, &rcodeErr)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if answered {
+			r.NameServers.Report(server, nil)
+		} else {
+			r.NameServers.Report(server, err)
+		}
+		if err == nil {
+			r.Logger.Debug("Resolved name", logger.Fields{
+				"host":       name,
+				"type":       dns.TypeToString[qtype],
+				"nameserver": server,
+				"answers":    reply.Answer,
+				"duration":   duration,
+			})
+			return reply, nil
+		}
+		if answered && rcodeErr.Rcode == dns.RcodeNameError {
+			return nil, err
+		}
+		r.Logger.Warn("Name server failed, trying next", logger.Fields{
+			"host":       name,
+			"nameserver": server,
+			"error":      err,
+		})
+	}
+	return nil, err
+}
+
+// exchangeServer - sends question of qtype for name to server over TCP,
+// temporary errors are retried with doubling wait within Timeout,
+// truncated reply is returned as ErrTruncated
+func (r *DNSResolver) exchangeServer(ctx context.Context, name, server string, qtype uint16) (reply *dns.Msg, dur time.Duration, err error) {
+	client := &dns.Client{
+		Net:          "tcp",
+		ReadTimeout:  r.StepTimeout,
+		WriteTimeout: r.StepTimeout,
+	}
+	msg := new(dns.Msg)
+	msg.RecursionDesired = true
+	msg.SetQuestion(dns.Fqdn(name), qtype)
+	retryWait := r.RetryWait
+	for {
+		if err = ctx.Err(); err != nil {
+			return nil, dur, err
+		}
+		var rtt time.Duration
+		reply, rtt, err = client.ExchangeContext(ctx, msg, server)
+		dur += rtt
+		if err != nil {
+			var netErr net.Error
+			if !errors.As(err, &netErr) || !netErr.Temporary() || ctx.Err() != nil || dur+retryWait >= r.Timeout {
+				return nil, dur, err
+			}
+			select {
+			case <-time.After(retryWait):
+			case <-ctx.Done():
+				return nil, dur, ctx.Err()
+			}
+			retryWait *= 2
+			continue
+		}
+		if reply.Rcode != dns.RcodeSuccess {
+			return nil, dur, &RcodeError{Name: name, NameServer: server, Rcode: reply.Rcode}
+		}
+		if reply.Truncated {
+			return nil, dur, ErrTruncated // retrying TCP gets the same reply
+		}
+		return reply, dur, nil
+	}
+}
+
+// answerAddrs - A and AAAA addresses of reply, ttl is the lowest TTL of
+// answers
+func answerAddrs(reply *dns.Msg) (ips []net.IP, ttl time.Duration) {
+	for i, rr := range reply.Answer {
+		switch a := rr.(type) {
+		case *dns.A:
+			ips = append(ips, a.A)
+		case *dns.AAAA:
+			ips = append(ips, a.AAAA)
+		}
+		if rrTTL := time.Duration(rr.Header().Ttl) * time.Second; i == 0 || rrTTL < ttl {
+			ttl = rrTTL
+		}
+	}
+	return ips, ttl
+}
+
+// RcodeError - name server replied with non success code
+type RcodeError struct {
+	Name, NameServer string
+	Rcode            int
+}
+
+func (e *RcodeError) Error() string {
+	return fmt.Sprintf(`ResolveName(%s, %s): %s`, e.Name, e.NameServer, dns.RcodeToString[e.Rcode])
+}
+
+// searchNames - names tried for host, absolute names and names with at
+// least Ndots dots are tried as is first
+func (r *DNSResolver) searchNames(host string) []string {
+	if strings.HasSuffix(host, ".") || len(r.Search) == 0 {
+		return []string{host}
+	}
+	names := make([]string, 0, len(r.Search)+1)
+	dots := strings.Count(host, ".")
+	if dots >= r.Ndots {
+		names = append(names, host)
+	}
+	for _, domain := range r.Search {
+		names = append(names, host+"."+domain)
+	}
+	if dots < r.Ndots {
+		names = append(names, host)
+	}
+	return names
+}
@@ -0,0 +1,168 @@
+package dialer
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// DoH default values
+const (
+	DefaultDoHTimeout = 5 * time.Second
+	DoHContentType    = "application/dns-message"
+
+	// dohMaxResponse - DNS message size limit
+	dohMaxResponse = 65535
+)
+
+// DoHResolver - RFC 8484 DNS over HTTPS Resolver, ttl is the lowest TTL
+// of answers, wrap it with NewCache to cache answers
+type DoHResolver struct {
+	// URL - DoH endpoint, e.g. https://dns.google/dns-query
+	URL string
+
+	// Method - http.MethodGet with dns query parameter, or http.MethodPost
+	// with DNS message body
+	Method string
+
+	// IPv6 - query AAAA records along with A records, unless lookup ctx
+	// says otherwise, see WithIPv6
+	IPv6 bool
+
+	Client *http.Client
+}
+
+// NewDoHResolver - returns GET DoH resolver of endpoint, bootstrap IP's,
+// if any, are dialed instead of resolving endpoint host, TLS server name
+// stays endpoint host. Proxies of environment are dialed as usual.
+func NewDoHResolver(endpoint string, bootstrap ...string) *DoHResolver {
+	transport := &http.Transport{
+		Proxy:             http.ProxyFromEnvironment,
+		ForceAttemptHTTP2: true,
+	}
+	if u, err := url.Parse(endpoint); err == nil && len(bootstrap) > 0 {
+		transport.DialContext = bootstrapDial(u.Hostname(), bootstrap)
+	}
+	return &DoHResolver{
+		URL:    endpoint,
+		Method: http.MethodGet,
+		Client: &http.Client{Transport: transport, Timeout: DefaultDoHTimeout},
+	}
+}
+
+// bootstrapDial - dials bootstrap IP's in order with port of address
+// when address host is host, other addresses, e.g. proxies, are dialed
+// as is
+func bootstrapDial(host string, bootstrap []string) func(ctx context.Context, network, address string) (net.Conn, error) {
+	var nd net.Dialer
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		dialHost, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.EqualFold(dialHost, host) {
+			return nd.DialContext(ctx, network, address)
+		}
+		for _, ip := range bootstrap {
+			var conn net.Conn
+			if conn, err = nd.DialContext(ctx, network, net.JoinHostPort(ip, port)); err == nil {
+				return conn, nil
+			}
+		}
+		return nil, err
+	}
+}
+
+// LookupAddrs - implements Resolver
+func (r *DoHResolver) LookupAddrs(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+	if lookupIPv6(ctx, r.IPv6) {
+		return queryDual(ctx, host, r.Query)
+	}
+	return r.Query(ctx, host, dns.TypeA)
+}
+
+// Query - sends single question of qtype, non success reply code is
+// returned as *RcodeError
+func (r *DoHResolver) Query(ctx context.Context, name string, qtype uint16) ([]net.IP, time.Duration, error) {
+	reply, err := r.Exchange(ctx, name, qtype)
+	if err != nil {
+		return nil, 0, err
+	}
+	ips, ttl := answerAddrs(reply)
+	return ips, ttl, nil
+}
+
+// LookupSRV - implements SRVResolver
+func (r *DoHResolver) LookupSRV(ctx context.Context, name string) ([]*net.SRV, time.Duration, error) {
+	reply, err := r.Exchange(ctx, name, dns.TypeSRV)
+	if err != nil {
+		return nil, 0, err
+	}
+	srvs, ttl := answerSRV(reply)
+	if len(srvs) == 0 {
+		return nil, 0, notFound(name)
+	}
+	return srvs, ttl, nil
+}
+
+// Exchange - sends single question of qtype and returns reply, non
+// success reply code is returned as *RcodeError
+func (r *DoHResolver) Exchange(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), qtype)
+	msg.RecursionDesired = true
+	msg.Id = 0 // RFC 8484 section 4.1, cache friendly
+	packed, err := msg.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	var req *http.Request
+	if r.Method == http.MethodPost {
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, r.URL, bytes.NewReader(packed))
+		if err == nil {
+			req.Header.Set("Content-Type", DoHContentType)
+		}
+	} else {
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, r.URL, nil)
+		if err == nil {
+			q := req.URL.Query()
+			q.Set("dns", base64.RawURLEncoding.EncodeToString(packed))
+			req.URL.RawQuery = q.Encode()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", DoHContentType)
+
+	res, err := r.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DoH %s: %s", r.URL, res.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, dohMaxResponse))
+	if err != nil {
+		return nil, err
+	}
+	reply := new(dns.Msg)
+	if err = reply.Unpack(body); err != nil {
+		return nil, fmt.Errorf("DoH %s: %w", r.URL, err)
+	}
+	if reply.Rcode != dns.RcodeSuccess {
+		return nil, &RcodeError{Name: name, NameServer: r.URL, Rcode: reply.Rcode}
+	}
+	return reply, nil
+}
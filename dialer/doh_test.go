@@ -0,0 +1,126 @@
+package dialer
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// dohServer - DoH stand-in answering every A query with 10.2.3.4 and
+// SRV query with node.internal:8080, TTL 60
+func dohServer(queries *int32) *httptest.Server {
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(queries, 1)
+		var packed []byte
+		var err error
+		if r.Method == http.MethodPost {
+			if r.Header.Get("Content-Type") != DoHContentType {
+				http.Error(w, "bad content type", http.StatusUnsupportedMediaType)
+				return
+			}
+			packed, err = io.ReadAll(r.Body)
+		} else {
+			packed, err = base64.RawURLEncoding.DecodeString(r.URL.Query().Get("dns"))
+		}
+		req := new(dns.Msg)
+		if err != nil || req.Unpack(packed) != nil {
+			http.Error(w, "bad query", http.StatusBadRequest)
+			return
+		}
+		m := new(dns.Msg)
+		m.SetReply(req)
+		switch {
+		case req.Question[0].Name == "missing.test.":
+			m.Rcode = dns.RcodeNameError
+		case req.Question[0].Qtype == dns.TypeA:
+			m.Answer = append(m.Answer, &dns.A{
+				Hdr: dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+				A:   net.ParseIP("10.2.3.4"),
+			})
+		case req.Question[0].Qtype == dns.TypeSRV:
+			m.Answer = append(m.Answer, &dns.SRV{
+				Hdr:    dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeSRV, Class: dns.ClassINET, Ttl: 60},
+				Port:   8080,
+				Target: "node.internal.",
+			})
+		}
+		reply, _ := m.Pack()
+		w.Header().Set("Content-Type", DoHContentType)
+		w.Write(reply)
+	}))
+}
+
+func TestDoHResolver(t *testing.T) {
+	var queries int32
+	ts := dohServer(&queries)
+	defer ts.Close()
+	_, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+
+	// test certificate is valid for example.com, reached via bootstrap IP
+	r := NewDoHResolver("https://example.com:"+port+"/dns-query", "127.0.0.1")
+	roots := x509.NewCertPool()
+	roots.AddCert(ts.Certificate())
+	r.Client.Transport.(*http.Transport).TLSClientConfig = &tls.Config{RootCAs: roots}
+	ctx := context.Background()
+
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		r.Method = method
+		ips, ttl, err := r.LookupAddrs(ctx, "api.test")
+		if err != nil || len(ips) != 1 || !ips[0].Equal(net.ParseIP("10.2.3.4")) || ttl != time.Minute {
+			t.Errorf("%s: expected answer with TTL got %v %s %v", method, ips, ttl, err)
+		}
+	}
+	if _, _, err := r.LookupAddrs(ctx, "missing.test"); !NegativeError(err) {
+		t.Errorf("Expected NXDOMAIN got %v", err)
+	}
+	srvs, ttl, err := r.LookupSRV(ctx, "_api._tcp.internal")
+	if err != nil || len(srvs) != 1 || srvs[0].Port != 8080 || ttl != time.Minute {
+		t.Errorf("Expected SRV answer with TTL got %v %s %v", srvs, ttl, err)
+	}
+
+	atomic.StoreInt32(&queries, 0)
+	c := NewCache(r)
+	for i := 0; i < 3; i++ {
+		if _, _, err := c.Lookup(ctx, "cached.test"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(&queries); n != 1 {
+		t.Errorf("Expected answer to be cached for its TTL got %d queries", n)
+	}
+}
+
+func TestBootstrapDialOtherHosts(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+
+	// proxy address is dialed as is, not through the bootstrap IP
+	dial := bootstrapDial("dns.test", []string{"192.0.2.1"})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	conn, err := dial(ctx, "tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Expected proxy address to be dialed got %v", err)
+	}
+	conn.Close()
+
+	dial = bootstrapDial("dns.test", []string{"127.0.0.1"})
+	if conn, err = dial(ctx, "tcp", "DNS.test:"+port); err != nil {
+		t.Fatalf("Expected bootstrap IP to be dialed got %v", err)
+	}
+	conn.Close()
+}
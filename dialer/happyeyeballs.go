@@ -0,0 +1,145 @@
+package dialer
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// DefaultFallbackDelay - RFC 8305 connection attempt delay, used when
+// net.Dialer FallbackDelay is not positive
+const DefaultFallbackDelay = 250 * time.Millisecond
+
+// Family - IP address family
+type Family int
+
+// Address families, IPv6 is preferred by default as by RFC 8305
+const (
+	IPv6 Family = iota
+	IPv4
+)
+
+func (f Family) String() string {
+	if f == IPv4 {
+		return "ipv4"
+	}
+	return "ipv6"
+}
+
+func familyOf(ip net.IP) Family {
+	if ip.To4() != nil {
+		return IPv4
+	}
+	return IPv6
+}
+
+// interleave - returns copy of ips alternating families, starting with
+// preferred one, order within family is kept (RFC 8305 section 4)
+func interleave(ips []net.IP, preferred Family) []net.IP {
+	var first, second []net.IP
+	for _, ip := range ips {
+		if familyOf(ip) == preferred {
+			first = append(first, ip)
+		} else {
+			second = append(second, ip)
+		}
+	}
+	if len(first) == 0 {
+		first, second = second, nil
+	}
+	out := make([]net.IP, 0, len(ips))
+	for i := 0; i < len(first) || i < len(second); i++ {
+		if i < len(first) {
+			out = append(out, first[i])
+		}
+		if i < len(second) {
+			out = append(out, second[i])
+		}
+	}
+	return out
+}
+
+// filterNetwork - addresses usable with tcp4 or tcp6 network
+func filterNetwork(network string, ips []net.IP) []net.IP {
+	var want Family
+	switch network {
+	case "tcp4":
+		want = IPv4
+	case "tcp6":
+		want = IPv6
+	default:
+		return ips
+	}
+	var out []net.IP
+	for _, ip := range ips {
+		if familyOf(ip) == want {
+			out = append(out, ip)
+		}
+	}
+	return out
+}
+
+// dialParallel - races connection attempts to ips in order, next attempt
+// starts after fallback delay or as soon as previous attempt fails. First
+// established connection wins, the others are cancelled or closed.
+func (d *Dialer) dialParallel(ctx context.Context, network, port string, ips []net.IP) (net.Conn, []*AttemptError) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	delay := d.FallbackDelay
+	if delay <= 0 {
+		delay = DefaultFallbackDelay
+	}
+
+	results := make(chan dialResult, len(ips))
+	next, pending := 0, 0
+	start := func() {
+		ip := ips[next]
+		next++
+		pending++
+		go func() {
+			conn, err := d.attempt(ctx, network, ip, port)
+			results <- dialResult{ip, conn, err}
+		}()
+	}
+	start()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var attempts []*AttemptError
+	for pending > 0 {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				go closeLate(results, pending)
+				return r.conn, nil
+			}
+			attempts = append(attempts, &AttemptError{IP: r.ip, Err: r.err})
+			if next < len(ips) {
+				start()
+				timer.Reset(delay)
+			}
+		case <-timer.C:
+			if next < len(ips) {
+				start()
+				timer.Reset(delay)
+			}
+		}
+	}
+	return nil, attempts
+}
+
+type dialResult struct {
+	ip   net.IP
+	conn net.Conn
+	err  error
+}
+
+// closeLate - closes connections of attempts finishing after the winner
+func closeLate(results <-chan dialResult, pending int) {
+	for ; pending > 0; pending-- {
+		if r := <-results; r.conn != nil {
+			r.conn.Close()
+		}
+	}
+}
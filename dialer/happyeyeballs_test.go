@@ -0,0 +1,50 @@
+package dialer
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestInterleave(t *testing.T) {
+	v4a, v4b := net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")
+	v6a, v6b := net.ParseIP("2001:db8::1"), net.ParseIP("2001:db8::2")
+	ips := []net.IP{v4a, v4b, v6a, v6b}
+
+	if out := interleave(ips, IPv6); !reflect.DeepEqual(out, []net.IP{v6a, v4a, v6b, v4b}) {
+		t.Errorf("Expected IPv6 first alternating got %v", out)
+	}
+	if out := interleave(ips, IPv4); !reflect.DeepEqual(out, []net.IP{v4a, v6a, v4b, v6b}) {
+		t.Errorf("Expected IPv4 first alternating got %v", out)
+	}
+	if out := interleave([]net.IP{v4a, v4b}, IPv6); !reflect.DeepEqual(out, []net.IP{v4a, v4b}) {
+		t.Errorf("Expected single family to keep order got %v", out)
+	}
+	if out := filterNetwork("tcp6", ips); !reflect.DeepEqual(out, []net.IP{v6a, v6b}) {
+		t.Errorf("Expected only IPv6 for tcp6 got %v", out)
+	}
+}
+
+func TestDialParallel(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+
+	d := New()
+	// 127.0.0.2 refuses, next address is tried without waiting for delay
+	conn, attempts := d.dialParallel(context.Background(), "tcp", port,
+		[]net.IP{net.ParseIP("127.0.0.2"), net.ParseIP("127.0.0.1")})
+	if conn == nil {
+		t.Fatal(attempts)
+	}
+	conn.Close()
+
+	if conn, attempts := d.dialParallel(context.Background(), "tcp", port,
+		[]net.IP{net.ParseIP("127.0.0.2")}); conn != nil || len(attempts) != 1 {
+		t.Errorf("Expected failed attempt got %v", attempts)
+	}
+}
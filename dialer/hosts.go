@@ -0,0 +1,108 @@
+package dialer
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultHosts - system hosts file
+const DefaultHosts = "/etc/hosts"
+
+// hostsCheckInterval - how often hosts file is checked for changes
+const hostsCheckInterval = 5 * time.Second
+
+// Hosts - hosts file lookup, file is re-read when it changes
+type Hosts struct {
+	Path string
+
+	mu      sync.Mutex
+	byName  map[string][]net.IP
+	modTime time.Time
+	size    int64
+	checked time.Time
+}
+
+// NewHosts - returns Hosts reading file at path on first lookup
+func NewHosts(path string) *Hosts {
+	return &Hosts{Path: path}
+}
+
+// Lookup - IP's of host in file order, nil if host is not listed
+func (h *Hosts) Lookup(host string) []net.IP {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.reload(time.Now())
+	return h.byName[normalizeHost(host)]
+}
+
+// LookupAddrs - implements Resolver, unlisted host is not found
+func (h *Hosts) LookupAddrs(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+	if ips := h.Lookup(host); len(ips) > 0 {
+		return ips, 0, nil
+	}
+	return nil, 0, notFound(host)
+}
+
+// reload - re-reads file when modified, missing file means no entries
+func (h *Hosts) reload(now time.Time) {
+	if now.Sub(h.checked) < hostsCheckInterval && h.byName != nil {
+		return
+	}
+	h.checked = now
+	fi, err := os.Stat(h.Path)
+	if err != nil {
+		h.byName = map[string][]net.IP{}
+		return
+	}
+	if h.byName != nil && fi.ModTime().Equal(h.modTime) && fi.Size() == h.size {
+		return
+	}
+	f, err := os.Open(h.Path)
+	if err != nil {
+		h.byName = map[string][]net.IP{}
+		return
+	}
+	defer f.Close()
+	h.byName = ParseHosts(f)
+	h.modTime, h.size = fi.ModTime(), fi.Size()
+}
+
+// ParseHosts - parses hosts file lines of IP followed by names, names are
+// case insensitive
+func ParseHosts(r io.Reader) map[string][]net.IP {
+	byName := make(map[string][]net.IP)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		f := strings.Fields(line)
+		if len(f) < 2 {
+			continue
+		}
+		addr := f[0]
+		if i := strings.IndexByte(addr, '%'); i >= 0 {
+			addr = addr[:i]
+		}
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			continue
+		}
+		for _, name := range f[1:] {
+			name = normalizeHost(name)
+			byName[name] = append(byName[name], ip)
+		}
+	}
+	return byName
+}
+
+func normalizeHost(host string) string {
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
@@ -0,0 +1,66 @@
+package dialer
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseHosts(t *testing.T) {
+	byName := ParseHosts(strings.NewReader(`
+127.0.0.1 localhost
+::1       localhost ip6-localhost # loopback
+10.0.0.5  API.internal api
+fe80::1%lo0 link.local
+bogus     ignored
+`))
+	if ips := byName["localhost"]; len(ips) != 2 || !ips[0].Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Errorf("Expected both localhost entries got %v", ips)
+	}
+	if ips := byName["api.internal"]; len(ips) != 1 || !ips[0].Equal(net.IPv4(10, 0, 0, 5)) {
+		t.Errorf("Expected case insensitive name got %v", ips)
+	}
+	if ips := byName["link.local"]; len(ips) != 1 {
+		t.Errorf("Expected zone to be stripped got %v", ips)
+	}
+	if _, ok := byName["ignored"]; ok {
+		t.Error("Expected line with invalid IP to be ignored")
+	}
+}
+
+func TestDialerResolveOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	if err := os.WriteFile(path, []byte("10.9.9.9 service.test\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	d := New()
+	d.Hosts = NewHosts(path)
+	d.DNS.NameServers = NewNameServers(serveDNS(t, "10.1.2.3"))
+	d.DNS.Search = nil
+	ctx := context.Background()
+
+	if ips, err := d.lookup(ctx, "service.test:80"); err != nil || !ips[0].Equal(net.IPv4(10, 9, 9, 9)) {
+		t.Errorf("Expected hosts entry got %v %v", ips, err)
+	}
+	if ips, err := d.lookup(ctx, "SERVICE.test.:80"); err != nil || !ips[0].Equal(net.IPv4(10, 9, 9, 9)) {
+		t.Errorf("Expected hosts entry for absolute upper case name got %v %v", ips, err)
+	}
+	if ips, err := d.lookup(ctx, "other.test:80"); err != nil || !ips[0].Equal(net.IPv4(10, 1, 2, 3)) {
+		t.Errorf("Expected name server answer got %v %v", ips, err)
+	}
+	if ips, err := d.lookup(ctx, "[::1]:80"); err != nil || !ips[0].Equal(net.IPv6loopback) {
+		t.Errorf("Expected IP literal got %v %v", ips, err)
+	}
+
+	d.Order = []Source{SourceDNS, SourceHosts}
+	if ips, err := d.lookup(ctx, "service.test:80"); err != nil || !ips[0].Equal(net.IPv4(10, 1, 2, 3)) {
+		t.Errorf("Expected name server first got %v %v", ips, err)
+	}
+	d.Order = []Source{SourceHosts}
+	if _, err := d.lookup(ctx, "other.test:80"); err == nil {
+		t.Error("Expected hosts only lookup of unlisted name to fail")
+	}
+}
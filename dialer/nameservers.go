@@ -0,0 +1,115 @@
+package dialer
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// NameServers default values
+const (
+	DefaultNameServerMaxFailures = 2
+	DefaultNameServerDownTime    = 30 * time.Second
+)
+
+// ServerHealth - health of a single name server
+type ServerHealth struct {
+	Address string
+
+	// Healthy - server is queried in configured order, unhealthy servers
+	// are skipped until DownUntil, or tried last when all are unhealthy
+	Healthy   bool
+	DownUntil time.Time
+
+	// Failures - consecutive failed queries, LastError of the latest
+	Failures  int
+	LastError error
+}
+
+// NameServers - ordered name servers with failover, server failing
+// MaxFailures consecutive queries is skipped for DownTime. Only
+// unreachable servers fail, any DNS reply counts as success.
+type NameServers struct {
+	MaxFailures int
+	DownTime    time.Duration
+
+	mu      sync.Mutex
+	servers []*ServerHealth
+}
+
+// NewNameServers - returns healthy name servers, addresses without port
+// default to port 53
+func NewNameServers(addrs ...string) *NameServers {
+	s := &NameServers{
+		MaxFailures: DefaultNameServerMaxFailures,
+		DownTime:    DefaultNameServerDownTime,
+	}
+	for _, addr := range addrs {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, "53")
+		}
+		s.servers = append(s.servers, &ServerHealth{Address: addr, Healthy: true})
+	}
+	return s
+}
+
+// Order - healthy servers in configured order followed by unhealthy ones
+// ordered by DownUntil
+func (s *NameServers) Order() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	order := make([]string, 0, len(s.servers))
+	var down []*ServerHealth
+	for _, h := range s.servers {
+		if h.Healthy || now.After(h.DownUntil) {
+			order = append(order, h.Address)
+			continue
+		}
+		i := len(down)
+		for i > 0 && down[i-1].DownUntil.After(h.DownUntil) {
+			i--
+		}
+		down = append(down, nil)
+		copy(down[i+1:], down[i:])
+		down[i] = h
+	}
+	for _, h := range down {
+		order = append(order, h.Address)
+	}
+	return order
+}
+
+// Report - records query outcome of server at addr
+func (s *NameServers) Report(addr string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, h := range s.servers {
+		if h.Address != addr {
+			continue
+		}
+		if err == nil {
+			h.Healthy, h.Failures, h.LastError = true, 0, nil
+			h.DownUntil = time.Time{}
+			return
+		}
+		h.Failures++
+		h.LastError = err
+		if h.Failures >= s.MaxFailures {
+			h.Healthy = false
+			h.DownUntil = time.Now().Add(s.DownTime)
+		}
+		return
+	}
+}
+
+// Health - snapshot of every server health in configured order
+func (s *NameServers) Health() []ServerHealth {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	health := make([]ServerHealth, len(s.servers))
+	for i, h := range s.servers {
+		health[i] = *h
+	}
+	return health
+}
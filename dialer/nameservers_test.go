@@ -0,0 +1,161 @@
+package dialer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// serveDNS - starts TCP name server answering every A query with ip
+func serveDNS(t *testing.T, ip string) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &dns.Server{Listener: l, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(req)
+		m.Answer = append(m.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+			A:   net.ParseIP(ip),
+		})
+		w.WriteMsg(m)
+	})}
+	go srv.ActivateAndServe()
+	t.Cleanup(func() { srv.Shutdown() })
+	return l.Addr().String()
+}
+
+func TestParseResolvConf(t *testing.T) {
+	conf, err := ParseResolvConf(strings.NewReader(`
+# comment
+nameserver 10.0.0.1
+nameserver fe80::1 ; trailing comment
+nameserver not-an-ip
+domain example.org
+search corp.example.com. example.com
+options timeout:1 ndots:3
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &ResolvConf{
+		Nameservers: []string{"10.0.0.1:53", "[fe80::1]:53"},
+		Search:      []string{"corp.example.com", "example.com"},
+		Ndots:       3,
+	}
+	if !reflect.DeepEqual(conf, expected) {
+		t.Errorf("Expected %+v got %+v", expected, conf)
+	}
+}
+
+func TestNameServersHealth(t *testing.T) {
+	s := NewNameServers("10.0.0.1", "10.0.0.2:5353")
+	s.Report("10.0.0.1:53", errors.New("timeout"))
+	if order := s.Order(); order[0] != "10.0.0.1:53" {
+		t.Errorf("Expected server to stay healthy after single failure got %v", order)
+	}
+	s.Report("10.0.0.1:53", errors.New("timeout"))
+	if order := s.Order(); !reflect.DeepEqual(order, []string{"10.0.0.2:5353", "10.0.0.1:53"}) {
+		t.Errorf("Expected dead server to be tried last got %v", order)
+	}
+	if h := s.Health(); h[0].Healthy || h[0].Failures != 2 || !h[1].Healthy {
+		t.Errorf("Unexpected health %+v", h)
+	}
+	s.Report("10.0.0.1:53", nil)
+	if h := s.Health(); !h[0].Healthy || h[0].Failures != 0 {
+		t.Errorf("Expected server to recover got %+v", h[0])
+	}
+}
+
+func TestDialerNameServerFailover(t *testing.T) {
+	dead, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadAddr := dead.Addr().String()
+	dead.Close()
+
+	d := New()
+	d.DNS.Search = nil
+	d.DNS.NameServers = NewNameServers(deadAddr, serveDNS(t, "10.1.2.3"))
+	for i := 0; i < 2; i++ {
+		ips, _, err := d.DNS.LookupAddrs(context.Background(), "example.com")
+		if err != nil || !ips[0].Equal(net.ParseIP("10.1.2.3")) {
+			t.Fatalf("Expected failover to second server got %v %v", ips, err)
+		}
+	}
+	if h := d.DNS.NameServers.Health(); h[0].Healthy {
+		t.Errorf("Expected dead server to be marked unhealthy got %+v", h[0])
+	}
+}
+
+func TestSearchNames(t *testing.T) {
+	r := &DNSResolver{Search: []string{"corp.example.com"}, Ndots: 1}
+	for host, expected := range map[string][]string{
+		"api":          {"api.corp.example.com", "api"},
+		"example.com":  {"example.com", "example.com.corp.example.com"},
+		"example.com.": {"example.com."},
+	} {
+		if names := r.searchNames(host); !reflect.DeepEqual(names, expected) {
+			t.Errorf("%s: expected %v got %v", host, expected, names)
+		}
+	}
+}
+
+func TestAnswerAddrsTTL(t *testing.T) {
+	reply := new(dns.Msg)
+	for i, ttl := range []uint32{0, 30} {
+		reply.Answer = append(reply.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: "api.test.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: ttl},
+			A:   net.IPv4(10, 0, 0, byte(i+1)),
+		})
+	}
+	if ips, ttl := answerAddrs(reply); len(ips) != 2 || ttl != 0 {
+		t.Errorf("Expected zero TTL of first answer to be kept got %v %s", ips, ttl)
+	}
+}
+
+func TestTruncatedReply(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var queries int32
+	srv := &dns.Server{Listener: l, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		atomic.AddInt32(&queries, 1)
+		m := new(dns.Msg)
+		m.SetReply(req)
+		m.Truncated = true
+		w.WriteMsg(m)
+	})}
+	go srv.ActivateAndServe()
+	defer srv.Shutdown()
+
+	r := NewDNSResolver()
+	r.NameServers = NewNameServers(l.Addr().String())
+	if _, _, err := r.LookupAddrs(context.Background(), "api.test."); !errors.Is(err, ErrTruncated) {
+		t.Errorf("Expected ErrTruncated got %v", err)
+	}
+	if n := atomic.LoadInt32(&queries); n != 1 {
+		t.Errorf("Expected truncated reply not to be retried got %d queries", n)
+	}
+}
+
+func TestNoNameServers(t *testing.T) {
+	r := NewDNSResolver()
+	r.NameServers = NewNameServers()
+	ctx := context.Background()
+	if _, _, err := r.LookupAddrs(ctx, "api.test."); !errors.Is(err, ErrNoSource) {
+		t.Errorf("Expected ErrNoSource got %v", err)
+	}
+	if _, _, err := r.LookupSRV(ctx, "_api._tcp.test."); !errors.Is(err, ErrNoSource) {
+		t.Errorf("Expected ErrNoSource for SRV got %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package dialer
+
+import (
+	"context"
+	"net"
+
+	"github.com/linkosmos/requestclient/logger"
+)
+
+// Source - where host addresses are looked up
+type Source int
+
+// Address sources
+const (
+	// SourceHosts - Hosts file
+	SourceHosts Source = iota
+
+	// SourceDNS - DNS resolver, cached by Cache
+	SourceDNS
+
+	// SourceSystem - system resolver of net.Dialer
+	SourceSystem
+)
+
+// DefaultOrder - hosts file, then name servers, then system resolver
+var DefaultOrder = []Source{SourceHosts, SourceDNS, SourceSystem}
+
+func (s Source) String() string {
+	switch s {
+	case SourceHosts:
+		return "hosts"
+	case SourceDNS:
+		return "dns"
+	case SourceSystem:
+		return "system"
+	}
+	return "unknown"
+}
+
+// lookupHost - looks up host through HostResolver, or in Order where
+// first source with addresses wins, hit is true when addresses were
+// served from Cache. AAAA records are looked up only with DualStack.
+func (d *Dialer) lookupHost(ctx context.Context, host string) (ips []net.IP, hit bool, err error) {
+	ctx = WithIPv6(ctx, d.DualStack)
+	if d.HostResolver != nil {
+		if c, ok := d.HostResolver.(*Cache); ok {
+			return c.Lookup(ctx, host)
+		}
+		ips, _, err = d.HostResolver.LookupAddrs(ctx, host)
+		if err == nil && len(ips) == 0 {
+			err = notFound(host)
+		}
+		return ips, false, err
+	}
+	order := d.Order
+	if order == nil {
+		order = DefaultOrder
+	}
+	err = ErrNoSource
+	for _, source := range order {
+		switch source {
+		case SourceHosts:
+			if d.Hosts == nil {
+				err = notFound(host)
+				continue
+			}
+			ips, _, err = d.Hosts.LookupAddrs(ctx, host)
+			if err != nil {
+				continue
+			}
+		case SourceDNS:
+			ips, hit, err = d.Cache.Lookup(ctx, host)
+		case SourceSystem:
+			ips, _, err = d.systemResolver().LookupAddrs(ctx, host)
+		default:
+			continue
+		}
+		if err == nil {
+			return ips, hit, nil
+		}
+		if ctx.Err() != nil {
+			return nil, false, ctx.Err()
+		}
+		d.Logger.Debug("Failed to resolve, trying next source", logger.Fields{
+			"host":   host,
+			"source": source,
+			"error":  err,
+		})
+	}
+	return nil, false, err
+}
+
+// systemResolver - resolver of net.Dialer, IPv4 only unless DualStack
+func (d *Dialer) systemResolver() *NetResolver {
+	r := &NetResolver{Resolver: d.Dialer.Resolver, Network: "ip4"}
+	if d.DualStack {
+		r.Network = "ip"
+	}
+	return r
+}
@@ -0,0 +1,50 @@
+package dialer
+
+import (
+	"net"
+	"strings"
+)
+
+// connectTo - address connection is made to instead of address, as with
+// curl --connect-to. ConnectTo keys are host:port or host for any port,
+// values host:port, host keeping the port or :port keeping the host.
+func (d *Dialer) connectTo(address string) string {
+	if len(d.ConnectTo) == 0 {
+		return address
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	host = normalizeHost(host)
+	to, ok := d.ConnectTo[net.JoinHostPort(host, port)]
+	if !ok {
+		if to, ok = d.ConnectTo[host]; !ok {
+			return address
+		}
+	}
+	toHost, toPort, err := net.SplitHostPort(to)
+	if err != nil {
+		toHost, toPort = strings.Trim(to, "[]"), ""
+	}
+	if toHost == "" {
+		toHost = host
+	}
+	if toPort == "" {
+		toPort = port
+	}
+	return net.JoinHostPort(toHost, toPort)
+}
+
+// hostOverride - fixed addresses of address host:port, as with curl
+// --resolve
+func (d *Dialer) hostOverride(address string) []net.IP {
+	if len(d.HostOverrides) == 0 {
+		return nil
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil
+	}
+	return d.HostOverrides[net.JoinHostPort(normalizeHost(host), port)]
+}
@@ -0,0 +1,84 @@
+package dialer
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DefaultResolvConf - system resolver configuration
+const DefaultResolvConf = "/etc/resolv.conf"
+
+// ResolvConf - name servers, search domains and ndots option of
+// resolv.conf, other directives are ignored
+type ResolvConf struct {
+	// Nameservers - host:port of name servers in file order
+	Nameservers []string
+
+	// Search - domains appended to names with fewer than Ndots dots
+	Search []string
+	Ndots  int
+}
+
+// LoadResolvConf - parses resolv.conf file at path
+func LoadResolvConf(path string) (*ResolvConf, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseResolvConf(f)
+}
+
+// ParseResolvConf - parses resolv.conf from r, ndots defaults to 1 and is
+// capped at 15 as by the system resolver
+func ParseResolvConf(r io.Reader) (*ResolvConf, error) {
+	conf := &ResolvConf{Ndots: 1}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexAny(line, "#;"); i >= 0 {
+			line = line[:i]
+		}
+		f := strings.Fields(line)
+		if len(f) < 2 {
+			continue
+		}
+		switch f[0] {
+		case "nameserver":
+			if net.ParseIP(f[1]) != nil {
+				conf.Nameservers = append(conf.Nameservers, net.JoinHostPort(f[1], "53"))
+			}
+		case "domain":
+			// domain and search are exclusive, last one wins
+			conf.Search = []string{trimDot(f[1])}
+		case "search":
+			conf.Search = conf.Search[:0:0]
+			for _, s := range f[1:] {
+				conf.Search = append(conf.Search, trimDot(s))
+			}
+		case "options":
+			for _, o := range f[1:] {
+				if !strings.HasPrefix(o, "ndots:") {
+					continue
+				}
+				n, err := strconv.Atoi(o[len("ndots:"):])
+				if err != nil || n < 0 {
+					continue
+				}
+				if n > 15 {
+					n = 15
+				}
+				conf.Ndots = n
+			}
+		}
+	}
+	return conf, scanner.Err()
+}
+
+func trimDot(s string) string {
+	return strings.TrimSuffix(s, ".")
+}
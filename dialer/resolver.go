@@ -0,0 +1,88 @@
+package dialer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+)
+
+// Resolver - resolves host addresses, ttl is how long they may be cached,
+// zero when unknown. Resolvers are safe for concurrent use, so a single
+// instance may be shared by many dialers.
+type Resolver interface {
+	LookupAddrs(ctx context.Context, host string) (ips []net.IP, ttl time.Duration, err error)
+}
+
+// LookupFunc - adapter to use ordinary function as Resolver
+type LookupFunc func(ctx context.Context, host string) (ips []net.IP, ttl time.Duration, err error)
+
+// LookupAddrs - calls f(ctx, host)
+func (f LookupFunc) LookupAddrs(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+	return f(ctx, host)
+}
+
+// NetResolver - Resolver of net package, TTL is unknown
+type NetResolver struct {
+	// Resolver - net.DefaultResolver if nil
+	Resolver *net.Resolver
+
+	// Network - "ip4", "ip6", or "ip" if empty
+	Network string
+}
+
+// LookupAddrs - implements Resolver
+func (r *NetResolver) LookupAddrs(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+	resolver, network := r.Resolver, r.Network
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	if network == "" {
+		network = "ip"
+	}
+	ips, err := resolver.LookupIP(ctx, network, host)
+	return ips, 0, err
+}
+
+// StaticResolver - fixed host addresses, host names are case insensitive
+type StaticResolver map[string][]net.IP
+
+// LookupAddrs - implements Resolver, unlisted host is not found
+func (r StaticResolver) LookupAddrs(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+	if ips, ok := r[host]; ok && len(ips) > 0 {
+		return ips, 0, nil
+	}
+	if ips := r[normalizeHost(host)]; len(ips) > 0 {
+		return ips, 0, nil
+	}
+	return nil, 0, notFound(host)
+}
+
+// ChainResolver - Resolvers tried in order until one has addresses
+type ChainResolver []Resolver
+
+// LookupAddrs - implements Resolver, returns error of the last resolver
+// when none has addresses
+func (c ChainResolver) LookupAddrs(ctx context.Context, host string) (ips []net.IP, ttl time.Duration, err error) {
+	err = ErrNoSource
+	for _, r := range c {
+		ips, ttl, err = r.LookupAddrs(ctx, host)
+		if err == nil && len(ips) > 0 {
+			return ips, ttl, nil
+		}
+		if ctx.Err() != nil {
+			return nil, 0, ctx.Err()
+		}
+		if err == nil {
+			err = notFound(host)
+		}
+	}
+	return nil, 0, err
+}
+
+// ErrNoSource - resolver has nothing to resolve with
+var ErrNoSource = errors.New("Dialer has no address source")
+
+func notFound(host string) error {
+	return &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
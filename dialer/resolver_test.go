@@ -0,0 +1,60 @@
+package dialer
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestChainResolver(t *testing.T) {
+	static := StaticResolver{"api.test": {net.ParseIP("10.0.0.1")}}
+	var calls int32
+	fallback := LookupFunc(func(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+		atomic.AddInt32(&calls, 1)
+		return []net.IP{net.ParseIP("10.0.0.2")}, time.Minute, nil
+	})
+	chain := ChainResolver{static, fallback}
+	ctx := context.Background()
+
+	if ips, _, err := chain.LookupAddrs(ctx, "API.test."); err != nil || !ips[0].Equal(net.ParseIP("10.0.0.1")) {
+		t.Errorf("Expected static address got %v %v", ips, err)
+	}
+	if ips, ttl, err := chain.LookupAddrs(ctx, "other.test"); err != nil || !ips[0].Equal(net.ParseIP("10.0.0.2")) || ttl != time.Minute {
+		t.Errorf("Expected fallback address got %v %s %v", ips, ttl, err)
+	}
+	if _, _, err := (ChainResolver{static}).LookupAddrs(ctx, "other.test"); err == nil {
+		t.Error("Expected unlisted host to fail")
+	}
+}
+
+func TestSharedCacheResolver(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+
+	var calls int32
+	shared := NewCache(LookupFunc(func(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+		atomic.AddInt32(&calls, 1)
+		return []net.IP{net.ParseIP("127.0.0.1")}, time.Minute, nil
+	}))
+	for i := 0; i < 3; i++ {
+		d := New()
+		d.HostResolver = shared
+		conn, err := d.Dial("tcp", net.JoinHostPort("service.test", port))
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("Expected dialers to share cached lookup got %d lookups", n)
+	}
+	if _, ttl, _ := shared.LookupAddrs(context.Background(), "service.test"); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("Expected remaining TTL got %s", ttl)
+	}
+}
@@ -0,0 +1,153 @@
+package dialer
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/linkosmos/requestclient/logger"
+	"github.com/miekg/dns"
+)
+
+// ErrNoService - SRV record target "." means service is not available
+var ErrNoService = errors.New("Service is not available")
+
+// SRVResolver - resolves SRV records of service name, ttl is how long
+// they may be cached, zero when unknown
+type SRVResolver interface {
+	LookupSRV(ctx context.Context, name string) (srvs []*net.SRV, ttl time.Duration, err error)
+}
+
+// LookupSRV - implements SRVResolver, queries name servers in order
+func (r *DNSResolver) LookupSRV(ctx context.Context, name string) ([]*net.SRV, time.Duration, error) {
+	reply, err := r.exchange(ctx, name, dns.TypeSRV)
+	if err != nil {
+		return nil, 0, err
+	}
+	srvs, ttl := answerSRV(reply)
+	if len(srvs) == 0 {
+		return nil, 0, notFound(name)
+	}
+	return srvs, ttl, nil
+}
+
+// answerSRV - SRV records of reply, ttl is the lowest TTL of them
+func answerSRV(reply *dns.Msg) (srvs []*net.SRV, ttl time.Duration) {
+	for _, rr := range reply.Answer {
+		srv, ok := rr.(*dns.SRV)
+		if !ok {
+			continue
+		}
+		if rrTTL := time.Duration(srv.Hdr.Ttl) * time.Second; len(srvs) == 0 || rrTTL < ttl {
+			ttl = rrTTL
+		}
+		srvs = append(srvs, &net.SRV{
+			Target:   srv.Target,
+			Port:     srv.Port,
+			Priority: srv.Priority,
+			Weight:   srv.Weight,
+		})
+	}
+	return srvs, ttl
+}
+
+// LookupSRV - implements SRVResolver, TTL is unknown
+func (r *NetResolver) LookupSRV(ctx context.Context, name string) ([]*net.SRV, time.Duration, error) {
+	resolver := r.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	_, srvs, err := resolver.LookupSRV(ctx, "", "", name)
+	return srvs, 0, err
+}
+
+// isService - whether host is SRV service name, e.g. _api._tcp.internal
+func isService(host string) bool {
+	return strings.HasPrefix(host, "_") &&
+		(strings.Contains(host, "._tcp.") || strings.Contains(host, "._udp."))
+}
+
+// dialService - dials SRV targets of service name in RFC 2782 order,
+// falling back to the next target when all its addresses fail
+func (d *Dialer) dialService(ctx context.Context, network, address, name string) (net.Conn, error) {
+	resolver := d.SRVResolver
+	if resolver == nil {
+		resolver = d.DNS
+	}
+	srvs, _, err := resolver.LookupSRV(ctx, strings.TrimSuffix(name, "."))
+	if err != nil {
+		return nil, err
+	}
+	if len(srvs) == 1 && srvs[0].Target == "." {
+		return nil, ErrNoService
+	}
+	dialErr := &DialError{Address: address}
+	for _, srv := range orderSRV(srvs) {
+		target := net.JoinHostPort(strings.TrimSuffix(srv.Target, "."), strconv.Itoa(int(srv.Port)))
+		conn, err := d.dialAddress(ctx, network, target, d.connectTo(target))
+		if err == nil {
+			return conn, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		var targetErr *DialError
+		if errors.As(err, &targetErr) {
+			dialErr.Attempts = append(dialErr.Attempts, targetErr.Attempts...)
+		} else {
+			dialErr.Attempts = append(dialErr.Attempts, &AttemptError{Err: err})
+		}
+		d.Logger.Warn("Failed to connect to service target, trying next", logger.Fields{
+			"service": name,
+			"target":  target,
+			"error":   err,
+		})
+	}
+	return nil, dialErr
+}
+
+// orderSRV - returns copy of srvs ordered by priority, within priority
+// by weighted random selection of RFC 2782
+func orderSRV(srvs []*net.SRV) []*net.SRV {
+	rest := make([]*net.SRV, len(srvs))
+	copy(rest, srvs)
+	// zero weights first within priority, as required for selection
+	sort.SliceStable(rest, func(i, j int) bool {
+		if rest[i].Priority != rest[j].Priority {
+			return rest[i].Priority < rest[j].Priority
+		}
+		return rest[i].Weight == 0 && rest[j].Weight != 0
+	})
+	out := make([]*net.SRV, 0, len(rest))
+	for len(rest) > 0 {
+		end := 1
+		for end < len(rest) && rest[end].Priority == rest[0].Priority {
+			end++
+		}
+		group := rest[:end]
+		for len(group) > 0 {
+			sum := 0
+			for _, srv := range group {
+				sum += int(srv.Weight)
+			}
+			pick := 0
+			if sum > 0 {
+				n := rand.Intn(sum + 1)
+				for running := 0; pick < len(group); pick++ {
+					if running += int(group[pick].Weight); running >= n {
+						break
+					}
+				}
+			}
+			out = append(out, group[pick])
+			group = append(group[:pick], group[pick+1:]...)
+		}
+		rest = rest[end:]
+	}
+	return out
+}
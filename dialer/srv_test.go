@@ -0,0 +1,151 @@
+package dialer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+type srvFunc func(ctx context.Context, name string) ([]*net.SRV, error)
+
+func (f srvFunc) LookupSRV(ctx context.Context, name string) ([]*net.SRV, time.Duration, error) {
+	srvs, err := f(ctx, name)
+	return srvs, 0, err
+}
+
+func TestOrderSRV(t *testing.T) {
+	srvs := []*net.SRV{
+		{Target: "c", Priority: 20, Weight: 5},
+		{Target: "a", Priority: 10, Weight: 0},
+		{Target: "b", Priority: 10, Weight: 100},
+	}
+	first := map[string]int{}
+	for i := 0; i < 200; i++ {
+		ordered := orderSRV(srvs)
+		if len(ordered) != 3 || ordered[2].Target != "c" {
+			t.Fatalf("Expected lower priority last got %v", ordered)
+		}
+		first[ordered[0].Target]++
+	}
+	if first["b"] < first["a"] {
+		t.Errorf("Expected heavier target to be picked first more often got %v", first)
+	}
+}
+
+func TestDialService(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	dead, _ := net.Listen("tcp", "127.0.0.1:0")
+	deadPort := dead.Addr().(*net.TCPAddr).Port
+	dead.Close()
+	livePort := l.Addr().(*net.TCPAddr).Port
+
+	d := New()
+	d.SRVResolver = srvFunc(func(ctx context.Context, name string) ([]*net.SRV, error) {
+		if name != "_api._tcp.internal" {
+			return nil, notFound(name)
+		}
+		return []*net.SRV{
+			{Target: "127.0.0.1.", Port: uint16(livePort), Priority: 10, Weight: 1},
+			{Target: "127.0.0.1.", Port: uint16(deadPort), Priority: 0, Weight: 1},
+		}, nil
+	})
+	conn, err := d.Dial("tcp", "_api._tcp.internal:80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port := conn.RemoteAddr().(*net.TCPAddr).Port; port != livePort {
+		t.Errorf("Expected fallback to %d got %d", livePort, port)
+	}
+	conn.Close()
+
+	d.SRVResolver = srvFunc(func(ctx context.Context, name string) ([]*net.SRV, error) {
+		return []*net.SRV{{Target: "127.0.0.1.", Port: uint16(deadPort)}}, nil
+	})
+	var dialErr *DialError
+	if _, err = d.Dial("tcp", "_api._tcp.internal:80"); !errors.As(err, &dialErr) || len(dialErr.Attempts) != 1 {
+		t.Errorf("Expected *DialError with attempt got %v", err)
+	}
+
+	d.SRVResolver = srvFunc(func(ctx context.Context, name string) ([]*net.SRV, error) {
+		return []*net.SRV{{Target: "."}}, nil
+	})
+	if _, err = d.Dial("tcp", "_api._tcp.internal:80"); err != ErrNoService {
+		t.Errorf("Expected ErrNoService got %v", err)
+	}
+}
+
+func TestDNSResolverLookupSRV(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &dns.Server{Listener: l, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(req)
+		for i := 1; i <= 2; i++ {
+			m.Answer = append(m.Answer, &dns.SRV{
+				Hdr:      dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeSRV, Class: dns.ClassINET, Ttl: 60},
+				Priority: uint16(i),
+				Weight:   10,
+				Port:     8080,
+				Target:   "node" + strconv.Itoa(i) + ".internal.",
+			})
+		}
+		w.WriteMsg(m)
+	})}
+	go srv.ActivateAndServe()
+	defer srv.Shutdown()
+
+	r := NewDNSResolver()
+	r.NameServers = NewNameServers(l.Addr().String())
+	srvs, ttl, err := r.LookupSRV(context.Background(), "_api._tcp.internal")
+	if err != nil || len(srvs) != 2 || srvs[0].Target != "node1.internal." || srvs[0].Port != 8080 || ttl != time.Minute {
+		t.Errorf("Expected SRV records with TTL got %v %s %v", srvs, ttl, err)
+	}
+}
+
+func TestSRVCache(t *testing.T) {
+	var queries int
+	c := NewSRVCache(srvFunc(func(ctx context.Context, name string) ([]*net.SRV, error) {
+		queries++
+		if name == "_missing._tcp.internal" {
+			return nil, notFound(name)
+		}
+		return []*net.SRV{{Target: "node.internal.", Port: 8080}}, nil
+	}))
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if srvs, ttl, err := c.LookupSRV(ctx, "_api._tcp.internal"); err != nil || len(srvs) != 1 || ttl <= 0 {
+			t.Fatalf("Expected cached SRV records got %v %s %v", srvs, ttl, err)
+		}
+		if _, _, err := c.LookupSRV(ctx, "_missing._tcp.internal"); err == nil {
+			t.Fatal("Expected not found")
+		}
+	}
+	if queries != 2 {
+		t.Errorf("Expected answers to be cached got %d queries", queries)
+	}
+	c.Delete("_api._tcp.internal")
+	c.LookupSRV(ctx, "_api._tcp.internal")
+	if queries != 3 {
+		t.Errorf("Expected deleted entry to be queried again got %d queries", queries)
+	}
+}
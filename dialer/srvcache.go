@@ -0,0 +1,103 @@
+package dialer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SRVCache - SRV records cache honoring record TTL. NXDOMAIN, SERVFAIL
+// and empty answers are cached for NegativeTTL, other errors are not.
+// SRVCache is itself an SRVResolver, safe for concurrent use, returned
+// records are shared and must not be modified.
+type SRVCache struct {
+	// MinTTL, MaxTTL - clamps of record TTL, zero MaxTTL means no limit
+	MinTTL, MaxTTL time.Duration
+
+	// NegativeTTL - how long NXDOMAIN, SERVFAIL and empty answers are cached
+	NegativeTTL time.Duration
+
+	resolver SRVResolver
+
+	mu      sync.Mutex
+	entries map[string]*srvEntry
+	purgeAt time.Time
+}
+
+type srvEntry struct {
+	srvs    []*net.SRV
+	err     error
+	expires time.Time
+}
+
+// NewSRVCache - returns SRVCache of resolver with default TTL clamps
+func NewSRVCache(resolver SRVResolver) *SRVCache {
+	return &SRVCache{
+		MinTTL:      DefaultCacheMinTTL,
+		MaxTTL:      DefaultCacheMaxTTL,
+		NegativeTTL: DefaultCacheNegativeTTL,
+		resolver:    resolver,
+		entries:     make(map[string]*srvEntry),
+	}
+}
+
+// LookupSRV - implements SRVResolver, ttl is the remaining entry TTL
+func (c *SRVCache) LookupSRV(ctx context.Context, name string) ([]*net.SRV, time.Duration, error) {
+	key := strings.ToLower(name)
+	now := time.Now()
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok && now.Before(e.expires) {
+		c.mu.Unlock()
+		return e.srvs, e.expires.Sub(now), e.err
+	}
+	c.mu.Unlock()
+
+	srvs, ttl, err := c.resolver.LookupSRV(ctx, name)
+	if err == nil && len(srvs) == 0 {
+		err = notFound(name)
+	}
+	e := &srvEntry{srvs: srvs, err: err}
+	switch {
+	case err == nil:
+		if ttl < c.MinTTL {
+			ttl = c.MinTTL
+		}
+		if c.MaxTTL > 0 && ttl > c.MaxTTL {
+			ttl = c.MaxTTL
+		}
+	case negativeSRV(err) && c.NegativeTTL > 0:
+		e.srvs, ttl = nil, c.NegativeTTL
+	default:
+		return nil, 0, err
+	}
+	e.expires = now.Add(ttl)
+	c.mu.Lock()
+	c.entries[key] = e
+	if now.After(c.purgeAt) {
+		for name, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, name)
+			}
+		}
+		c.purgeAt = now.Add(cachePurgeInterval)
+	}
+	c.mu.Unlock()
+	return e.srvs, ttl, e.err
+}
+
+// Delete - removes entry of name
+func (c *SRVCache) Delete(name string) {
+	c.mu.Lock()
+	delete(c.entries, strings.ToLower(name))
+	c.mu.Unlock()
+}
+
+// negativeSRV - whether err is a negative answer, including not found
+// of resolvers without reply codes
+func negativeSRV(err error) bool {
+	var dnsErr *net.DNSError
+	return NegativeError(err) || (errors.As(err, &dnsErr) && dnsErr.IsNotFound)
+}
@@ -0,0 +1,23 @@
+package dialer
+
+import "context"
+
+// Trace - dialer hooks, carried by dial context
+type Trace struct {
+	// DNSCacheHit, if non-nil, is called after host lookup, hit is
+	// true when host addresses were served from cache
+	DNSCacheHit func(host string, hit bool)
+}
+
+type traceKey struct{}
+
+// WithTrace - returns context carrying dialer trace hooks
+func WithTrace(ctx context.Context, t *Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, t)
+}
+
+// ContextTrace - returns trace hooks of ctx, nil if none
+func ContextTrace(ctx context.Context) *Trace {
+	t, _ := ctx.Value(traceKey{}).(*Trace)
+	return t
+}
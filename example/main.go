@@ -2,7 +2,7 @@ package main
 
 import (
 	"fmt"
-	"net/url"
+	"log"
 
 	"github.com/linkosmos/requestclient"
 )
@@ -11,9 +11,11 @@ func main() {
 
 	client := requestclient.New(nil)
 
-	u, _ := url.Parse("http://www.example.com")
-
-	responseClient, _ := client.Do(client.GET(u)) // For higher level API
+	responseClient, err := client.Request(requestclient.GET, "http://www.example.com").Do() // For higher level API
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer responseClient.Body.Close()
 
 	fmt.Println(responseClient.Status)
 }
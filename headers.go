@@ -0,0 +1,77 @@
+package requestclient
+
+import (
+	"net/http"
+	"sync"
+)
+
+// headerSet - client default headers, guarded for concurrent use
+type headerSet struct {
+	mu sync.RWMutex
+	h  http.Header
+}
+
+// DefaultHeaders - returns a copy of client default headers, changes to
+// the copy do not affect the client
+func (r *RequestClient) DefaultHeaders() http.Header {
+	r.headers.mu.RLock()
+	defer r.headers.mu.RUnlock()
+	return cloneHeader(r.headers.h)
+}
+
+// SetHeader - sets client default header, replacing any existing values,
+// affects only requests formed after the call
+func (r *RequestClient) SetHeader(key, value string) {
+	r.updateHeaders(func(h http.Header) { h.Set(key, value) })
+}
+
+// AddHeader - adds value to client default header
+func (r *RequestClient) AddHeader(key, value string) {
+	r.updateHeaders(func(h http.Header) { h.Add(key, value) })
+}
+
+// DelHeader - deletes client default header
+func (r *RequestClient) DelHeader(key string) {
+	r.updateHeaders(func(h http.Header) { h.Del(key) })
+}
+
+// ReplaceHeaders - replaces all client default headers with a copy of h
+func (r *RequestClient) ReplaceHeaders(h http.Header) {
+	r.updateHeaders(func(old http.Header) {
+		for k := range old {
+			delete(old, k)
+		}
+		for k, v := range h {
+			old[k] = append([]string(nil), v...)
+		}
+	})
+}
+
+// updateHeaders - copy-on-write, requests already formed keep their own copy
+func (r *RequestClient) updateHeaders(fn func(http.Header)) {
+	r.headers.mu.Lock()
+	defer r.headers.mu.Unlock()
+	h := cloneHeader(r.headers.h)
+	fn(h)
+	r.headers.h = h
+}
+
+// OverrideHeader - applies per request overrides on top of dst, every key
+// present in override replaces dst values, keys with no values are deleted
+func OverrideHeader(dst, override http.Header) {
+	for k, v := range override {
+		if len(v) == 0 {
+			dst.Del(k)
+			continue
+		}
+		dst[http.CanonicalHeaderKey(k)] = append([]string(nil), v...)
+	}
+}
+
+func cloneHeader(h http.Header) http.Header {
+	c := make(http.Header, len(h))
+	for k, v := range h {
+		c[k] = append([]string(nil), v...)
+	}
+	return c
+}
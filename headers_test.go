@@ -0,0 +1,56 @@
+package requestclient
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+func TestRequestHeaderIsolation(t *testing.T) {
+	client := New(nil)
+	u, _ := url.Parse("http://www.example.com")
+
+	req := client.GET(u)
+	req.Header.Set("Authorization", "secret")
+	if got := client.GET(u).Header.Get("Authorization"); got != "" {
+		t.Errorf("Expected Authorization not to leak got %s", got)
+	}
+
+	client.SetHeader("X-Default", "1")
+	if got := req.Header.Get("X-Default"); got != "" {
+		t.Errorf("Expected formed request to keep its headers got %s", got)
+	}
+
+	req = client.NewRequestHeader(GET, u, nil, http.Header{
+		"X-Default":  {"2"},
+		"Connection": nil,
+	})
+	if got := req.Header.Get("X-Default"); got != "2" {
+		t.Errorf("Expected override X-Default => 2 got %s", got)
+	}
+	if got := req.Header.Get("Connection"); got != "" {
+		t.Errorf("Expected Connection to be deleted got %s", got)
+	}
+	if got := client.DefaultHeaders().Get("Connection"); got != "Keep-Alive" {
+		t.Errorf("Expected client defaults untouched got %s", got)
+	}
+}
+
+func TestSetHeaderConcurrent(t *testing.T) {
+	client := New(nil)
+	u, _ := url.Parse("http://www.example.com")
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			client.SetHeader("X-Counter", "1")
+		}()
+		go func() {
+			defer wg.Done()
+			client.GET(u).Header.Set("X-Request", "1")
+		}()
+	}
+	wg.Wait()
+}
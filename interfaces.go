@@ -1,6 +1,7 @@
 package requestclient
 
 import (
+	"context"
 	"net"
 	"net/http"
 )
@@ -10,6 +11,12 @@ type DialDialer interface {
 	Dial(network, address string) (net.Conn, error)
 }
 
+// ContextDialer - DialDialer aware of request context, when Dialer
+// implements it cancellation and deadlines reach dialing.
+type ContextDialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
 // RoundTripper is an interface representing the ability to execute a
 // single HTTP transaction, obtaining the Response for a given Request.
 //
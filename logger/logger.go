@@ -0,0 +1,110 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// Fields - structured log fields such as host, address, error, duration
+type Fields map[string]interface{}
+
+// Logger - leveled structured logger
+type Logger interface {
+	Debug(msg string, fields Fields)
+	Info(msg string, fields Fields)
+	Warn(msg string, fields Fields)
+	Error(msg string, fields Fields)
+}
+
+// Nop - Logger discarding everything, default logger
+type Nop struct{}
+
+// Debug - implements Logger
+func (Nop) Debug(string, Fields) {}
+
+// Info - implements Logger
+func (Nop) Info(string, Fields) {}
+
+// Warn - implements Logger
+func (Nop) Warn(string, Fields) {}
+
+// Error - implements Logger
+func (Nop) Error(string, Fields) {}
+
+// Std - Logger writing to standard log package logger as
+// "level msg key=value ..." lines, fields sorted by key
+type Std struct {
+	Logger *log.Logger
+}
+
+// NewStd - returns Logger writing to l, log.Default() if nil
+func NewStd(l *log.Logger) *Std {
+	if l == nil {
+		l = log.Default()
+	}
+	return &Std{Logger: l}
+}
+
+// Debug - implements Logger
+func (s *Std) Debug(msg string, fields Fields) { s.print("debug", msg, fields) }
+
+// Info - implements Logger
+func (s *Std) Info(msg string, fields Fields) { s.print("info", msg, fields) }
+
+// Warn - implements Logger
+func (s *Std) Warn(msg string, fields Fields) { s.print("warn", msg, fields) }
+
+// Error - implements Logger
+func (s *Std) Error(msg string, fields Fields) { s.print("error", msg, fields) }
+
+func (s *Std) print(level, msg string, fields Fields) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s %s", level, msg)
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&buf, " %s=%v", k, fields[k])
+	}
+	s.Logger.Output(3, buf.String())
+}
+
+// FieldLogger - subset of *logrus.Logger and *logrus.Entry used by Logrus
+type FieldLogger interface {
+	WithFields(logrus.Fields) *logrus.Entry
+}
+
+// Logrus - Logger adapter for logrus
+type Logrus struct {
+	Logger FieldLogger
+}
+
+// NewLogrus - returns Logger writing to l, logrus.StandardLogger() if nil
+func NewLogrus(l FieldLogger) *Logrus {
+	if l == nil {
+		l = logrus.StandardLogger()
+	}
+	return &Logrus{Logger: l}
+}
+
+// Debug - implements Logger
+func (l *Logrus) Debug(msg string, fields Fields) { l.entry(fields).Debug(msg) }
+
+// Info - implements Logger
+func (l *Logrus) Info(msg string, fields Fields) { l.entry(fields).Info(msg) }
+
+// Warn - implements Logger
+func (l *Logrus) Warn(msg string, fields Fields) { l.entry(fields).Warn(msg) }
+
+// Error - implements Logger
+func (l *Logrus) Error(msg string, fields Fields) { l.entry(fields).Error(msg) }
+
+func (l *Logrus) entry(fields Fields) *logrus.Entry {
+	return l.Logger.WithFields(logrus.Fields(fields))
+}
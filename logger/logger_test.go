@@ -0,0 +1,16 @@
+package logger
+
+import (
+	"bytes"
+	"log"
+	"testing"
+)
+
+func TestStd(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewStd(log.New(&buf, "", 0))
+	l.Warn("Failed to resolve", Fields{"host": "example.com", "error": "timeout"})
+	if got, expected := buf.String(), "warn Failed to resolve error=timeout host=example.com\n"; got != expected {
+		t.Errorf("Expected %q got %q", expected, got)
+	}
+}
@@ -7,11 +7,13 @@ import (
 
 // Request methods
 const (
-	GET    = "GET"
-	HEAD   = "HEAD"
-	POST   = "POST"
-	PUT    = "PUT"
-	DELETE = "DELETE"
+	GET     = "GET"
+	HEAD    = "HEAD"
+	POST    = "POST"
+	PUT     = "PUT"
+	PATCH   = "PATCH"
+	DELETE  = "DELETE"
+	OPTIONS = "OPTIONS"
 )
 
 // GET - request
@@ -23,3 +25,28 @@ func (r *RequestClient) GET(u *url.URL) *http.Request {
 func (r *RequestClient) HEAD(u *url.URL) *http.Request {
 	return r.NewRequest(HEAD, u, nil)
 }
+
+// POST - request, body is encoded with NewBody
+func (r *RequestClient) POST(u *url.URL, body interface{}) (*http.Request, error) {
+	return r.NewBodyRequest(POST, u, body)
+}
+
+// PUT - request, body is encoded with NewBody
+func (r *RequestClient) PUT(u *url.URL, body interface{}) (*http.Request, error) {
+	return r.NewBodyRequest(PUT, u, body)
+}
+
+// PATCH - request, body is encoded with NewBody
+func (r *RequestClient) PATCH(u *url.URL, body interface{}) (*http.Request, error) {
+	return r.NewBodyRequest(PATCH, u, body)
+}
+
+// DELETE - request, body is encoded with NewBody, nil for no body
+func (r *RequestClient) DELETE(u *url.URL, body interface{}) (*http.Request, error) {
+	return r.NewBodyRequest(DELETE, u, body)
+}
+
+// OPTIONS - request, body is encoded with NewBody, nil for no body
+func (r *RequestClient) OPTIONS(u *url.URL, body interface{}) (*http.Request, error) {
+	return r.NewBodyRequest(OPTIONS, u, body)
+}
@@ -0,0 +1,100 @@
+package requestclient
+
+import (
+	"net/http"
+	"time"
+)
+
+// Middleware - RoundTripper decorator
+type Middleware func(RoundTripper) RoundTripper
+
+// RoundTripperFunc - adapter to use ordinary function as RoundTripper
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip - implements RoundTripper
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Chain - decorates rt with middlewares, first middleware is outermost
+// and sees request first
+func Chain(rt RoundTripper, mw ...Middleware) RoundTripper {
+	for i := len(mw) - 1; i >= 0; i-- {
+		rt = mw[i](rt)
+	}
+	return rt
+}
+
+// Use - adds middlewares applied to both Do and RoundTrip. Middlewares
+// are applied in order they were added, first is outermost, all of them
+// wrap built-in retry, rate limit, bulkhead and circuit breaker layers.
+// Use replaces Transport, it must not be called concurrently with requests.
+func (r *RequestClient) Use(mw ...Middleware) {
+	r.middlewares = append(r.middlewares, mw...)
+	r.Transport = Chain(r.transport, r.middlewares...)
+}
+
+// httpMiddleware - adapts http.RoundTripper decorators of subpackages
+func httpMiddleware(fn func(http.RoundTripper) http.RoundTripper) Middleware {
+	return func(next RoundTripper) RoundTripper {
+		return fn(next)
+	}
+}
+
+// WithHeaders - sets headers on every request, replacing existing values
+func WithHeaders(h http.Header) Middleware {
+	return func(next RoundTripper) RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			OverrideHeader(req.Header, h)
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// WithBasicAuth - sets basic auth credentials on every request
+func WithBasicAuth(user, password string) Middleware {
+	return func(next RoundTripper) RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			req.SetBasicAuth(user, password)
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// WithBearerAuth - sets Authorization: Bearer header on every request with
+// token returned by token func, so tokens can be refreshed
+func WithBearerAuth(token func(*http.Request) (string, error)) Middleware {
+	return func(next RoundTripper) RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			t, err := token(req)
+			if err != nil {
+				if req.Body != nil {
+					req.Body.Close()
+				}
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Header.Set("Authorization", "Bearer "+t)
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// WithLogging - logs method, URL, status or error and duration of every
+// request, logf is compatible with log.Printf
+func WithLogging(logf func(format string, args ...interface{})) Middleware {
+	return func(next RoundTripper) RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			res, err := next.RoundTrip(req)
+			if err != nil {
+				logf("%s %s failed after %s: %s", req.Method, req.URL, time.Since(start), err)
+				return res, err
+			}
+			logf("%s %s %s in %s", req.Method, req.URL, res.Status, time.Since(start))
+			return res, err
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package requestclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestUse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Order", r.Header.Get("X-Order"))
+	}))
+	defer ts.Close()
+
+	order := func(name string) Middleware {
+		return func(next RoundTripper) RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				req = req.Clone(req.Context())
+				req.Header.Add("X-Order", name)
+				return next.RoundTrip(req)
+			})
+		}
+	}
+	client := New(nil)
+	client.Use(order("first"), order("second"))
+	client.Use(WithHeaders(http.Header{"X-Third": {"3"}}))
+
+	u, _ := url.Parse(ts.URL)
+	for name, send := range map[string]func(*http.Request) (*http.Response, error){
+		"Do":        client.Do,
+		"RoundTrip": client.RoundTrip,
+	} {
+		res, err := send(client.GET(u))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if got := res.Header.Get("X-Order"); got != "first" {
+			t.Errorf("%s: Expected first middleware to be outermost got %s", name, got)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package requestclient
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Multipart - streaming multipart/form-data body builder. Parts are read
+// only when request body is sent, through an io.Pipe, so files are never
+// buffered in memory. Use it as body of POST, PUT or RequestBuilder.Body.
+type Multipart struct {
+	boundary string
+	parts    []*multipartPart
+	err      error
+}
+
+type multipartPart struct {
+	header textproto.MIMEHeader
+	open   func() (io.Reader, error)
+
+	// once - content can be read only once
+	once bool
+
+	// size of part content, -1 if unknown
+	size int64
+}
+
+// NewMultipart - returns empty multipart body with random boundary
+func NewMultipart() *Multipart {
+	return &Multipart{
+		boundary: multipart.NewWriter(ioutil.Discard).Boundary(),
+	}
+}
+
+// Field - adds form field part
+func (m *Multipart) Field(name, value string) *Multipart {
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"`, escapeQuotes(name)))
+	m.parts = append(m.parts, &multipartPart{
+		header: h,
+		open: func() (io.Reader, error) {
+			return strings.NewReader(value), nil
+		},
+		size: int64(len(value)),
+	})
+	return m
+}
+
+// File - adds file part read from disk, Content-Type is guessed from file
+// extension. File is opened when request body is sent.
+func (m *Multipart) File(field, path string) *Multipart {
+	fi, err := os.Stat(path)
+	if err != nil {
+		m.err = err
+		return m
+	}
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	if contentType == "" {
+		contentType = ContentTypeBinary
+	}
+	m.addPart(field, filepath.Base(path), contentType, fi.Size(), func() (io.Reader, error) {
+		return os.Open(path)
+	})
+	return m
+}
+
+// Reader - adds file part read from r, size -1 if unknown. Empty content
+// type defaults to application/octet-stream. r is not closed, it's owned
+// by the caller.
+func (m *Multipart) Reader(field, filename, contentType string, r io.Reader, size int64) *Multipart {
+	if size < 0 {
+		size = readerLength(r)
+	}
+	if contentType == "" {
+		contentType = ContentTypeBinary
+	}
+	m.addPart(field, filename, contentType, size, func() (io.Reader, error) {
+		return r, nil
+	})
+	m.parts[len(m.parts)-1].once = true
+	return m
+}
+
+func (m *Multipart) addPart(field, filename, contentType string, size int64, open func() (io.Reader, error)) {
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		escapeQuotes(field), escapeQuotes(filename)))
+	h.Set("Content-Type", contentType)
+	m.parts = append(m.parts, &multipartPart{header: h, open: open, size: size})
+}
+
+// ContentType - multipart/form-data content type with boundary
+func (m *Multipart) ContentType() string {
+	return "multipart/form-data; boundary=" + m.boundary
+}
+
+// Len - total body length in bytes, -1 if any part size is unknown
+func (m *Multipart) Len() int64 {
+	var cw countWriter
+	mw := multipart.NewWriter(&cw)
+	mw.SetBoundary(m.boundary)
+	total := int64(0)
+	for _, p := range m.parts {
+		if p.size < 0 {
+			return -1
+		}
+		mw.CreatePart(p.header)
+		total += p.size
+	}
+	mw.Close()
+	return total + cw.n
+}
+
+// Body - returns streaming request body, sent chunked when length is unknown
+func (m *Multipart) Body() (*Body, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &Body{
+		Reader:      &multipartReader{m: m},
+		ContentType: m.ContentType(),
+		Length:      m.Len(),
+	}, nil
+}
+
+// rewindable - whether body can be sent more than once
+func (m *Multipart) rewindable() bool {
+	for _, p := range m.parts {
+		if p.once {
+			return false
+		}
+	}
+	return true
+}
+
+// write - writes all parts to w
+func (m *Multipart) write(w io.Writer) error {
+	mw := multipart.NewWriter(w)
+	mw.SetBoundary(m.boundary)
+	for _, p := range m.parts {
+		pw, err := mw.CreatePart(p.header)
+		if err != nil {
+			return err
+		}
+		r, err := p.open()
+		if err != nil {
+			return err
+		}
+		n, err := io.Copy(pw, r)
+		if c, ok := r.(io.Closer); ok && !p.once {
+			c.Close() // files opened by File
+		}
+		if err != nil {
+			return err
+		}
+		if p.size >= 0 && n != p.size {
+			return fmt.Errorf("Multipart part %s: expected %d bytes, got %d",
+				p.header.Get("Content-Disposition"), p.size, n)
+		}
+	}
+	return mw.Close()
+}
+
+// multipartReader - starts writing parts into pipe on first Read, so
+// nothing is opened for requests that are never sent
+type multipartReader struct {
+	m    *Multipart
+	once sync.Once
+	pr   *io.PipeReader
+}
+
+func (r *multipartReader) start() {
+	pr, pw := io.Pipe()
+	r.pr = pr
+	go func() {
+		pw.CloseWithError(r.m.write(pw))
+	}()
+}
+
+func (r *multipartReader) Read(p []byte) (int, error) {
+	r.once.Do(r.start)
+	if r.pr == nil {
+		return 0, io.ErrClosedPipe
+	}
+	return r.pr.Read(p)
+}
+
+// Close - stops writing parts, closing files already opened
+func (r *multipartReader) Close() error {
+	r.once.Do(func() {})
+	if r.pr != nil {
+		return r.pr.Close()
+	}
+	return nil
+}
+
+type countWriter struct {
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
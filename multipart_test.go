@@ -0,0 +1,74 @@
+package requestclient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type closeTracker struct {
+	*strings.Reader
+	closed bool
+}
+
+func (c *closeTracker) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestMultipart(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "report.txt")
+	if err := ioutil.WriteFile(path, []byte("file content"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength <= 0 {
+			t.Errorf("Expected known ContentLength got %d", r.ContentLength)
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Error(err)
+			return
+		}
+		if got := r.FormValue("name"); got != "gopher" {
+			t.Errorf("Expected field name => gopher got %s", got)
+		}
+		f, fh, err := r.FormFile("report")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer f.Close()
+		data, _ := ioutil.ReadAll(f)
+		if fh.Filename != "report.txt" || string(data) != "file content" {
+			t.Errorf("Expected report.txt with 'file content' got %s with %s", fh.Filename, data)
+		}
+		if got := fh.Header.Get("Content-Type"); !strings.HasPrefix(got, "text/plain") {
+			t.Errorf("Expected part Content-Type text/plain got %s", got)
+		}
+	}))
+	defer ts.Close()
+
+	data := &closeTracker{Reader: strings.NewReader("abc")}
+	m := NewMultipart().
+		Field("name", "gopher").
+		File("report", path).
+		Reader("data", "data.bin", "", data, -1)
+	res, err := New(nil).Request(POST, ts.URL).Body(m).Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if data.closed {
+		t.Error("Expected caller's reader to be left open")
+	}
+
+	if _, err := NewMultipart().File("missing", filepath.Join(dir, "missing")).Body(); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error got %v", err)
+	}
+}
@@ -4,6 +4,12 @@ import (
 	"fmt"
 	"net/http"
 	"time"
+
+	"github.com/linkosmos/requestclient/breaker"
+	"github.com/linkosmos/requestclient/bulkhead"
+	"github.com/linkosmos/requestclient/dialer"
+	"github.com/linkosmos/requestclient/logger"
+	"github.com/linkosmos/requestclient/ratelimit"
 )
 
 // DefaultUserAgent - default user agent for this request client package
@@ -12,12 +18,18 @@ var DefaultUserAgent = fmt.Sprintf("Go RequestClient %s", VERSION)
 // RequestClient options default values
 const (
 	DefaultDialerTimeout                = 30 * time.Second
+	DefaultDialerDeadline               = 30 * time.Second
 	DefaultDialerDualStack              = false
 	DefaultDialerKeepAlive              = 30 * time.Second
+	DefaultDNSCacheMinTTL               = dialer.DefaultCacheMinTTL
+	DefaultDNSCacheMaxTTL               = dialer.DefaultCacheMaxTTL
+	DefaultDNSCacheNegativeTTL          = dialer.DefaultCacheNegativeTTL
+	DefaultDNSCacheMaxStale             = dialer.DefaultCacheMaxStale
 	DefaultTransportMaxTries            = 3
 	DefaultTransportDisableKeepAlives   = false
 	DefaultTransportDisableCompression  = false
 	DefaultTransportMaxIdleConnsPerHost = http.DefaultMaxIdleConnsPerHost
+	DefaultTransportIdleConnTimeout     = 90 * time.Second
 	DefaultClientTimeout                = 1 * time.Minute // Default 3 min
 	DefaultTLSInsecureSkipVerify        = false
 )
@@ -27,13 +39,18 @@ func NewOptions() (op *Options) {
 	op = &Options{
 		Headers:                      make(http.Header),
 		DialerTimeout:                DefaultDialerTimeout,
-		DialerDeadline:               time.Now().Add(DefaultDialerTimeout),
+		DialerDeadline:               DefaultDialerDeadline,
 		DialerDualStack:              DefaultDialerDualStack,
 		DialerKeepAlive:              DefaultDialerKeepAlive,
+		DNSCacheMinTTL:               DefaultDNSCacheMinTTL,
+		DNSCacheMaxTTL:               DefaultDNSCacheMaxTTL,
+		DNSCacheNegativeTTL:          DefaultDNSCacheNegativeTTL,
+		DNSCacheMaxStale:             DefaultDNSCacheMaxStale,
 		TransportMaxTries:            DefaultTransportMaxTries,
 		TransportDisableKeepAlives:   DefaultTransportDisableKeepAlives,
 		TransportDisableCompression:  DefaultTransportDisableCompression,
 		TransportMaxIdleConnsPerHost: DefaultTransportMaxIdleConnsPerHost,
+		TransportIdleConnTimeout:     DefaultTransportIdleConnTimeout,
 		ClientTimeout:                DefaultClientTimeout,
 		TLSInsecureSkipVerify:        DefaultTLSInsecureSkipVerify,
 	}
@@ -51,32 +68,116 @@ type Options struct {
 	// Dialer
 	////////////////////////////////
 	// Timeout is the maximum amount of time a dial will wait for
-	// a connect to complete.
+	// a connect to complete, applied to every connection attempt.
 	//
 	// With or without a timeout, the operating system may impose
 	// its own earlier timeout. For instance, TCP timeouts are
 	// often around 3 minutes.
 	DialerTimeout time.Duration // The default is no timeout.
 
-	// Deadline is the absolute point in time after which dials
-	// will fail. If Timeout is set, it may fail earlier.
-	// Zero means no deadline, or dependent on the operating system
-	// as with the Timeout option.
-	DialerDeadline time.Time
+	// Deadline is the budget of a single dial, including DNS lookup
+	// and connection attempts to every resolved address, counted from
+	// the start of each dial. If Timeout is set, an attempt may fail
+	// earlier. Zero means no budget.
+	DialerDeadline time.Duration
 
 	// DualStack allows a single dial to attempt to establish
 	// multiple IPv4 and IPv6 connections and to return the first
 	// established connection when the network is "tcp" and the
 	// destination is a host name that has multiple address family
-	// DNS records.
+	// DNS records. Both A and AAAA records are resolved and connection
+	// attempts are raced as by RFC 8305 Happy Eyeballs.
 	DialerDualStack bool
 
+	// PreferredFamily - address family of the first DualStack connection
+	// attempt, dialer.IPv6 by default.
+	DialerPreferredFamily dialer.Family
+
+	// FallbackDelay - how long DualStack connection attempt may take
+	// before the next address is tried in parallel. Zero means 250ms.
+	DialerFallbackDelay time.Duration
+
+	// AddrOrder - order resolved addresses are tried in until one connects:
+	// dialer.AddrRandom, dialer.AddrRoundRobin or
+	// dialer.AddrLeastRecentlyFailed.
+	DialerAddrOrder dialer.AddrOrder
+
+	// UnhealthyTime - how long address that failed to connect is tried
+	// after all others. Zero means 30s.
+	DialerUnhealthyTime time.Duration
+
 	// KeepAlive specifies the keep-alive period for an active
 	// network connection.
 	// If zero, keep-alives are not enabled. Network protocols
 	// that do not support keep-alives ignore this field.
 	DialerKeepAlive time.Duration
 
+	// DNSCacheMinTTL, DNSCacheMaxTTL clamp TTL of records cached by
+	// dialer, entries are refreshed in background before they expire.
+	// Zero DNSCacheMaxTTL means no upper limit.
+	DNSCacheMinTTL, DNSCacheMaxTTL time.Duration
+
+	// DNSCacheNegativeTTL - how long NXDOMAIN, SERVFAIL and empty answers
+	// are cached, zero disables negative caching.
+	DNSCacheNegativeTTL time.Duration
+
+	// DNSCacheMaxStale - how long expired record may be used while it's
+	// refreshed in background, zero resolves expired records synchronously.
+	DNSCacheMaxStale time.Duration
+
+	// DNSServers - name servers, host or host:port, queried in order with
+	// failover, a server failing repeatedly is skipped for a while.
+	// Empty means name servers of DNSResolvConf.
+	DNSServers []string
+
+	// DNSResolvConf - resolv.conf path read for name servers, search
+	// domains and ndots option. Empty means /etc/resolv.conf.
+	DNSResolvConf string
+
+	// DNSOverHTTPS - RFC 8484 DNS over HTTPS endpoint queried instead of
+	// DNSServers, e.g. https://dns.google/dns-query. Answers are cached as
+	// with name servers.
+	DNSOverHTTPS string
+
+	// DNSOverHTTPSBootstrap - IP addresses of DNSOverHTTPS endpoint host,
+	// dialed without resolving it.
+	DNSOverHTTPSBootstrap []string
+
+	// DNSOverHTTPSMethod - GET or POST wire format, empty means GET.
+	DNSOverHTTPSMethod string
+
+	// DNSHostsFile - hosts file consulted before name servers, re-read
+	// when it changes. Empty means /etc/hosts.
+	DNSHostsFile string
+
+	// DNSResolveOrder - address sources tried in order until one has
+	// addresses, nil means hosts file, name servers, system resolver.
+	// IP literal addresses are dialed without lookup.
+	DNSResolveOrder []dialer.Source
+
+	// Resolver, if non-nil, resolves every host instead of DNSResolveOrder
+	// sources, e.g. dialer.ChainResolver, dialer.StaticResolver or
+	// dialer.NewCache wrapping any of them. One Resolver may be shared by
+	// many clients, so they don't resolve the same hosts separately.
+	Resolver dialer.Resolver
+
+	// SRVResolver, if non-nil, resolves SRV records of service names, e.g.
+	// dialer.NetResolver or dialer.NewSRVCache wrapping any SRV resolver.
+	// Nil means cached DNSOverHTTPS when set, otherwise cached name servers.
+	SRVResolver dialer.SRVResolver
+
+	// HostOverrides - fixed IP addresses of host:port consulted before any
+	// lookup, as with curl --resolve, e.g. "api.example.com:443":
+	// {"10.0.0.7"}.
+	HostOverrides map[string][]string
+
+	// ConnectTo - authority connections are made to instead of the
+	// requested one, as with curl --connect-to, while Host header and TLS
+	// server name stay the requested ones. Keys are host:port or host,
+	// values host:port, host or :port, e.g. "api.example.com:443":
+	// "canary.internal:8443".
+	ConnectTo map[string]string
+
 	//
 	////////////////////////////////
 	// Transport
@@ -100,6 +201,10 @@ type Options struct {
 	// (keep-alive) to keep per-host.
 	TransportMaxIdleConnsPerHost int
 
+	// IdleConnTimeout, if non-zero, is the maximum amount of time an idle
+	// (keep-alive) connection will remain idle before closing itself.
+	TransportIdleConnTimeout time.Duration
+
 	// RequestTimeout, if non-zero, specifies the amount of time for the entire
 	// request. This includes dialing (if necessary), the response header as well
 	// as the entire body.
@@ -111,6 +216,39 @@ type Options struct {
 	// time does not include the time to read the response body.
 	TransportResponseHeaderTimeout time.Duration
 
+	// RetryPolicy, if non-nil, decides retries of every request from its
+	// method, error and response status, see BackoffPolicy. Request bodies
+	// are rewound between attempts. Setting it disables TransportMaxTries.
+	RetryPolicy RetryPolicy
+
+	// CircuitBreaker, if non-nil, enables per host circuit breaker, while
+	// breaker is open requests fail fast with *breaker.OpenError, see
+	// breaker.NewConfig for defaults. Breaker states are exposed through
+	// RequestClient.Breakers. Setting it disables TransportMaxTries, so every
+	// attempt passes the breaker, use RetryPolicy for retries.
+	CircuitBreaker *breaker.Config
+
+	// RateLimit, if non-nil, enables global and per host token bucket
+	// rate limits enforced before request enters Transport, waiting
+	// respects request context. Limiter is exposed through
+	// RequestClient.RateLimiter.
+	RateLimit *ratelimit.Config
+
+	// Bulkhead, if non-nil, caps in-flight requests and open connections
+	// for the whole client and per host, each limit with bounded wait
+	// queue and queue timeout. Queue depth is exposed through
+	// RequestClient.Bulkheads. Idle keep-alive connections count against
+	// connection limits, once client wide limit is reached while some are
+	// idle, idle connections of every host are closed.
+	Bulkhead *bulkhead.Config
+
+	// OnStats, if non-nil, is called with statistics of every request:
+	// header and body durations, errors and retries of TransportMaxTries or
+	// RetryPolicy, retried attempts are marked pending.
+	// See stats.Aggregator for per host aggregation and Prometheus
+	// exposition.
+	OnStats func(*Stats)
+
 	//
 	////////////////////////////////
 	// Client
@@ -149,6 +287,17 @@ type Options struct {
 
 	// Request headers
 	Headers http.Header
+
+	//
+	////////////////////////////////
+	// Logging
+	////////////////////////////////
+	//
+
+	// Logger receives dialer resolve and fallback events with structured
+	// fields, see logger.NewStd and logger.NewLogrus adapters.
+	// Nil means no logging.
+	Logger logger.Logger
 }
 
 //
@@ -0,0 +1,188 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited - request would exceed rate limit
+var ErrRateLimited = errors.New("Rate limit exceeded")
+
+// IdleTimeout - host bucket left full for this long is dropped, it's
+// recreated full on next request
+const IdleTimeout = time.Minute
+
+// Limit - requests per second with burst, zero Rate means no limit
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Config - global and per host rate limits
+type Config struct {
+	// Global - limit shared by all requests
+	Global Limit
+
+	// Hosts - limits by host pattern, such as api.example.com or
+	// *.example.com, matched with path.Match. Exact host match wins,
+	// otherwise the longest matching pattern. Every host matching a
+	// pattern gets its own bucket. Hosts and patterns are case insensitive.
+	Hosts map[string]Limit
+
+	// NonBlocking, if true, fails request with ErrRateLimited
+	// immediately instead of waiting for a token
+	NonBlocking bool
+}
+
+// Limiter - token bucket rate limiter for global and per host limits
+type Limiter struct {
+	config   *Config
+	global   *Bucket
+	limits   map[string]Limit // lower case Config.Hosts
+	patterns []string         // longest first
+
+	mu        sync.Mutex
+	hosts     map[string]*Bucket
+	nextSweep time.Time
+}
+
+// New - returns Limiter for config
+func New(c *Config) *Limiter {
+	l := &Limiter{
+		config: c,
+		limits: make(map[string]Limit, len(c.Hosts)),
+		hosts:  make(map[string]*Bucket),
+	}
+	if c.Global.Rate > 0 {
+		l.global = NewBucket(c.Global.Rate, c.Global.Burst)
+	}
+	for pattern, limit := range c.Hosts {
+		pattern = strings.ToLower(pattern)
+		l.limits[pattern] = limit
+		l.patterns = append(l.patterns, pattern)
+	}
+	sort.Slice(l.patterns, func(i, j int) bool {
+		if len(l.patterns[i]) != len(l.patterns[j]) {
+			return len(l.patterns[i]) > len(l.patterns[j])
+		}
+		return l.patterns[i] < l.patterns[j]
+	})
+	return l
+}
+
+// Host - returns token bucket of host, nil if host has no limit
+func (l *Limiter) Host(host string) *Bucket {
+	host = strings.ToLower(host)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if now.After(l.nextSweep) {
+		l.sweep(now)
+	}
+	if b, ok := l.hosts[host]; ok {
+		return b
+	}
+	limit, ok := l.match(host)
+	if !ok || limit.Rate <= 0 {
+		return nil
+	}
+	b := NewBucket(limit.Rate, limit.Burst)
+	l.hosts[host] = b
+	return b
+}
+
+// sweep - drops host buckets idle and full for IdleTimeout, dropping
+// such bucket loses no state
+func (l *Limiter) sweep(now time.Time) {
+	for host, b := range l.hosts {
+		if b.idle(now, IdleTimeout) {
+			delete(l.hosts, host)
+		}
+	}
+	l.nextSweep = now.Add(IdleTimeout)
+}
+
+func (l *Limiter) match(host string) (Limit, bool) {
+	if limit, ok := l.limits[host]; ok {
+		return limit, true
+	}
+	for _, pattern := range l.patterns {
+		if ok, _ := path.Match(pattern, host); ok {
+			return l.limits[pattern], true
+		}
+	}
+	return Limit{}, false
+}
+
+// Wait - blocks until request to host is allowed by global and host limits
+// or ctx is done. In NonBlocking mode it returns ErrRateLimited instead
+// of waiting.
+func (l *Limiter) Wait(ctx context.Context, host string) error {
+	buckets := make([]*Bucket, 0, 2)
+	if l.global != nil {
+		buckets = append(buckets, l.global)
+	}
+	if b := l.Host(host); b != nil {
+		buckets = append(buckets, b)
+	}
+	now := time.Now()
+	var wait time.Duration
+	for _, b := range buckets {
+		if w := b.reserve(now); w > wait {
+			wait = w
+		}
+	}
+	if wait == 0 {
+		return nil
+	}
+	refund := func() {
+		for _, b := range buckets {
+			b.refund()
+		}
+	}
+	if l.config.NonBlocking {
+		refund()
+		return ErrRateLimited
+	}
+	if deadline, ok := ctx.Deadline(); ok && now.Add(wait).After(deadline) {
+		refund()
+		return fmt.Errorf("%w: %s wait exceeds request deadline", ErrRateLimited, wait)
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		refund()
+		return ctx.Err()
+	}
+}
+
+// Transport - returns RoundTripper waiting for rate limits before passing
+// request to next
+func (l *Limiter) Transport(next http.RoundTripper) http.RoundTripper {
+	return &transport{limiter: l, next: next}
+}
+
+type transport struct {
+	limiter *Limiter
+	next    http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context(), req.URL.Hostname()); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	return t.next.RoundTrip(req)
+}
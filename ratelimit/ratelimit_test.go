@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	l := New(&Config{
+		Hosts: map[string]Limit{
+			"*.example.com":   {Rate: 1, Burst: 1},
+			"api.example.com": {Rate: 100, Burst: 2},
+		},
+		NonBlocking: true,
+	})
+	ctx := context.Background()
+
+	if err := l.Wait(ctx, "www.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Wait(ctx, "www.example.com"); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited got %v", err)
+	}
+	if err := l.Wait(ctx, "cdn.example.com"); err != nil {
+		t.Errorf("Expected every host to have its own bucket got %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := l.Wait(ctx, "api.example.com"); err != nil {
+			t.Errorf("Expected exact host limit with burst 2 got %v", err)
+		}
+	}
+	if err := l.Wait(ctx, "other.org"); err != nil {
+		t.Errorf("Expected unlimited host got %v", err)
+	}
+}
+
+func TestLimiterWaitCancel(t *testing.T) {
+	l := New(&Config{Global: Limit{Rate: 0.1, Burst: 1}})
+	l.Wait(context.Background(), "example.com")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx, "example.com"); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited for wait past deadline got %v", err)
+	}
+}
+
+func TestLimiterHostKeys(t *testing.T) {
+	l := New(&Config{
+		Hosts:       map[string]Limit{"*.Example.com": {Rate: 100, Burst: 1}},
+		NonBlocking: true,
+	})
+	ctx := context.Background()
+	if err := l.Wait(ctx, "API.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Wait(ctx, "api.example.com"); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected host keys to be case insensitive got %v", err)
+	}
+
+	l.mu.Lock()
+	l.sweep(time.Now().Add(2 * IdleTimeout))
+	n := len(l.hosts)
+	l.mu.Unlock()
+	if n != 0 {
+		t.Errorf("Expected idle full bucket to be dropped got %d buckets", n)
+	}
+}
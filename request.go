@@ -2,6 +2,7 @@ package requestclient
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -11,9 +12,13 @@ import (
 
 // NewRequest returns a new Request given a method, URL, and optional body.
 //
+// Request gets its own copy of client default headers, so it's safe to
+// modify Request.Header without affecting the client or other requests.
+//
 // If the provided body is also an io.Closer, the returned
 // Request.Body is set to body and will be closed by the Client
-// methods Do, Post, and PostForm, and Transport.RoundTrip.
+// methods Do, Post, and PostForm, and Transport.RoundTrip. Body known
+// to be empty is sent as http.NoBody with ContentLength 0.
 func (r *RequestClient) NewRequest(method string, u *url.URL, body io.Reader) (req *http.Request) {
 	rc, ok := body.(io.ReadCloser)
 	if !ok && body != nil {
@@ -25,19 +30,71 @@ func (r *RequestClient) NewRequest(method string, u *url.URL, body io.Reader) (r
 		Proto:      r.RequestProto,
 		ProtoMajor: r.RequestProtoMajor,
 		ProtoMinor: r.RequestProtoMinor,
-		Header:     r.Headers,
+		Header:     r.DefaultHeaders(),
 		Body:       rc,
 		Host:       u.Host,
 	}
 	if body != nil {
-		switch v := body.(type) {
-		case *bytes.Buffer:
-			req.ContentLength = int64(v.Len())
-		case *bytes.Reader:
-			req.ContentLength = int64(v.Len())
-		case *strings.Reader:
-			req.ContentLength = int64(v.Len())
+		req.GetBody = getBodyFunc(body)
+		setContentLength(req, readerLength(body))
+	}
+	return req
+}
+
+// setContentLength - sets known body length n, known empty body is
+// replaced with http.NoBody so it isn't sent chunked
+func setContentLength(req *http.Request, n int64) {
+	switch {
+	case n == 0:
+		req.ContentLength = 0
+		req.Body = http.NoBody
+		req.GetBody = func() (io.ReadCloser, error) { return http.NoBody, nil }
+	case n > 0:
+		req.ContentLength = n
+	}
+}
+
+// getBodyFunc - returns func reproducing body for retries and redirects,
+// nil if body can be read only once
+func getBodyFunc(body io.Reader) func() (io.ReadCloser, error) {
+	switch v := body.(type) {
+	case *bytes.Buffer:
+		buf := v.Bytes()
+		return func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(buf)), nil
+		}
+	case *bytes.Reader:
+		snapshot := *v
+		return func() (io.ReadCloser, error) {
+			r := snapshot
+			return ioutil.NopCloser(&r), nil
+		}
+	case *strings.Reader:
+		snapshot := *v
+		return func() (io.ReadCloser, error) {
+			r := snapshot
+			return ioutil.NopCloser(&r), nil
+		}
+	case *multipartReader:
+		if !v.m.rewindable() {
+			return nil
+		}
+		return func() (io.ReadCloser, error) {
+			return &multipartReader{m: v.m}, nil
 		}
 	}
+	return nil
+}
+
+// NewRequestHeader - same as NewRequest, header overrides are applied on top
+// of client default headers, see OverrideHeader
+func (r *RequestClient) NewRequestHeader(method string, u *url.URL, body io.Reader, override http.Header) (req *http.Request) {
+	req = r.NewRequest(method, u, body)
+	OverrideHeader(req.Header, override)
 	return req
 }
+
+// NewRequestContext - same as NewRequest, returned Request carries ctx
+func (r *RequestClient) NewRequestContext(ctx context.Context, method string, u *url.URL, body io.Reader) *http.Request {
+	return r.NewRequest(method, u, body).WithContext(ctx)
+}
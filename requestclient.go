@@ -1,11 +1,18 @@
 package requestclient
 
 import (
+	"context"
 	"crypto/tls"
+	"net"
 	"net/http"
+	"strings"
 
-	"github.com/facebookgo/httpcontrol"
+	"github.com/linkosmos/requestclient/breaker"
+	"github.com/linkosmos/requestclient/bulkhead"
 	"github.com/linkosmos/requestclient/dialer"
+	"github.com/linkosmos/requestclient/logger"
+	"github.com/linkosmos/requestclient/ratelimit"
+	"github.com/linkosmos/requestclient/transport"
 )
 
 // -
@@ -15,12 +22,16 @@ const (
 	RequestProtoMajor = 1
 )
 
+// Stats - statistics of a single request reported by Transport
+type Stats = transport.Stats
+
 // RequestClient - http.: Transport, Client wrapper
 type RequestClient struct {
 
-	// A Header represents the key-value pairs in an HTTP header.
-	// Used for every http.Request formed by RequestClient
-	Headers http.Header // requesting headers
+	// headers - default headers of every http.Request formed by
+	// RequestClient, each request gets its own copy. Read with
+	// DefaultHeaders, change with SetHeader, AddHeader, DelHeader.
+	headers *headerSet
 
 	RequestProto string
 
@@ -55,6 +66,22 @@ type RequestClient struct {
 	// and additionally handles HTTP details such as cookies and
 	// redirects.
 	Client ClientRequester
+
+	// Breakers - per host circuit breakers, nil unless
+	// Options.CircuitBreaker is set
+	Breakers *breaker.Set
+
+	// RateLimiter - global and per host rate limiter, nil unless
+	// Options.RateLimit is set
+	RateLimiter *ratelimit.Limiter
+
+	// Bulkheads - in-flight request and connection limits, nil unless
+	// Options.Bulkhead is set
+	Bulkheads *bulkhead.Set
+
+	// transport - Transport before middlewares added with Use
+	transport   RoundTripper
+	middlewares []Middleware
 }
 
 // New - returns Request Client
@@ -62,14 +89,9 @@ func New(op *Options) (r *RequestClient) {
 	if op == nil {
 		op = NewOptions()
 	}
-	d := dialer.New()
-	d.Timeout = op.DialerTimeout
-	d.Deadline = op.DialerDeadline
-	d.DualStack = op.DialerDualStack
-	d.KeepAlive = op.DialerKeepAlive
-	d.DualStack = op.DialerDualStack
+	d := newDialer(op)
 	r = &RequestClient{
-		Headers:           op.Headers,
+		headers:           &headerSet{h: cloneHeader(op.Headers)},
 		RequestProto:      RequestProto,
 		RequestProtoMinor: RequestProtoMinor,
 		RequestProtoMajor: RequestProtoMajor,
@@ -80,25 +102,144 @@ func New(op *Options) (r *RequestClient) {
 	}
 
 	// Setting up TRANSPORT
-	r.Transport = &httpcontrol.Transport{
-		Dial:                  r.Dialer.Dial,
+	base := &transport.Transport{
 		TLSClientConfig:       r.TLS,
 		MaxTries:              op.TransportMaxTries,
 		DisableKeepAlives:     op.TransportDisableKeepAlives,
 		DisableCompression:    op.TransportDisableCompression,
 		MaxIdleConnsPerHost:   op.TransportMaxIdleConnsPerHost,
+		IdleConnTimeout:       op.TransportIdleConnTimeout,
 		RequestTimeout:        op.TransportRequestTimeout,
 		ResponseHeaderTimeout: op.TransportResponseHeaderTimeout,
+		Stats:                 op.OnStats,
 	}
+	if cd, ok := r.Dialer.(ContextDialer); ok {
+		base.DialContext = cd.DialContext
+	} else {
+		base.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+			return r.Dialer.Dial(network, address)
+		}
+	}
+	if op.Bulkhead != nil {
+		r.Bulkheads = bulkhead.NewSet(op.Bulkhead)
+		r.Bulkheads.CloseIdle = base.CloseIdleConnections
+		base.DialContext = r.Bulkheads.DialContext(base.DialContext)
+	}
+	// Built-in middlewares, outermost first
+	var chain []Middleware
+	if op.RetryPolicy != nil {
+		base.MaxTries = 0
+		chain = append(chain, retryMiddleware(op.RetryPolicy, op.OnStats))
+	}
+	if op.RateLimit != nil {
+		r.RateLimiter = ratelimit.New(op.RateLimit)
+		chain = append(chain, httpMiddleware(r.RateLimiter.Transport))
+	}
+	if r.Bulkheads != nil {
+		chain = append(chain, httpMiddleware(r.Bulkheads.Transport))
+	}
+	if op.CircuitBreaker != nil {
+		base.MaxTries = 0 // retries must not bypass breaker
+		r.Breakers = breaker.NewSet(op.CircuitBreaker)
+		chain = append(chain, httpMiddleware(r.Breakers.Transport))
+	}
+	chain = append(chain, func(next RoundTripper) RoundTripper {
+		return &timingTransport{next: next}
+	})
+	r.transport = Chain(base, chain...)
+	r.Transport = r.transport
 
-	// Setting up CLIENT, higher level API of TRANSPORT
+	// Setting up CLIENT, higher level API of TRANSPORT, it always sends
+	// through current Transport, so Use and replacing Transport affect Do
 	r.Client = &http.Client{
-		Transport: r.Transport,
-		Timeout:   op.ClientTimeout,
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return r.Transport.RoundTrip(req)
+		}),
+		Timeout: op.ClientTimeout,
 	}
 	return r
 }
 
+// newDialer - dialer configured by Dialer and DNS options
+func newDialer(op *Options) *dialer.Dialer {
+	d := dialer.New()
+	d.Timeout = op.DialerTimeout
+	d.Budget = op.DialerDeadline
+	d.DualStack = op.DialerDualStack
+	d.KeepAlive = op.DialerKeepAlive
+	d.PreferredFamily = op.DialerPreferredFamily
+	d.FallbackDelay = op.DialerFallbackDelay
+	d.AddrOrder = op.DialerAddrOrder
+	if op.DialerUnhealthyTime > 0 {
+		d.Health.UnhealthyTime = op.DialerUnhealthyTime
+	}
+	if op.Logger != nil {
+		d.Logger = op.Logger
+		d.DNS.Logger = op.Logger
+	}
+	if op.DNSResolvConf != "" {
+		conf, err := dialer.LoadResolvConf(op.DNSResolvConf)
+		if err != nil {
+			d.Logger.Warn("Failed to load resolv.conf", logger.Fields{
+				"path":  op.DNSResolvConf,
+				"error": err,
+			})
+		} else {
+			d.DNS.SetResolvConf(conf)
+		}
+	}
+	if len(op.DNSServers) > 0 {
+		d.DNS.NameServers = dialer.NewNameServers(op.DNSServers...)
+	}
+	if op.DNSHostsFile != "" {
+		d.Hosts = dialer.NewHosts(op.DNSHostsFile)
+	}
+	d.Order = op.DNSResolveOrder
+	d.HostResolver = op.Resolver
+	d.HostOverrides = hostOverrides(op.HostOverrides, d.Logger)
+	d.ConnectTo = make(map[string]string, len(op.ConnectTo))
+	for from, to := range op.ConnectTo {
+		d.ConnectTo[strings.ToLower(from)] = to
+	}
+	if op.DNSOverHTTPS != "" {
+		doh := dialer.NewDoHResolver(op.DNSOverHTTPS, op.DNSOverHTTPSBootstrap...)
+		if op.DNSOverHTTPSMethod != "" {
+			doh.Method = op.DNSOverHTTPSMethod
+		}
+		d.Cache = dialer.NewCache(doh)
+		d.SRVResolver = dialer.NewSRVCache(doh)
+	}
+	if op.SRVResolver != nil {
+		d.SRVResolver = op.SRVResolver
+	}
+	d.Cache.MinTTL = op.DNSCacheMinTTL
+	d.Cache.MaxTTL = op.DNSCacheMaxTTL
+	d.Cache.NegativeTTL = op.DNSCacheNegativeTTL
+	d.Cache.MaxStale = op.DNSCacheMaxStale
+	return d
+}
+
+// hostOverrides - parses IP's of host:port overrides, invalid ones are
+// logged and skipped
+func hostOverrides(overrides map[string][]string, log logger.Logger) map[string][]net.IP {
+	parsed := make(map[string][]net.IP, len(overrides))
+	for hostport, addrs := range overrides {
+		hostport = strings.ToLower(hostport)
+		for _, addr := range addrs {
+			ip := net.ParseIP(addr)
+			if ip == nil {
+				log.Warn("Invalid host override IP", logger.Fields{
+					"address": hostport,
+					"ip":      addr,
+				})
+				continue
+			}
+			parsed[hostport] = append(parsed[hostport], ip)
+		}
+	}
+	return parsed
+}
+
 // Do - sends an HTTP request and returns an HTTP response, following
 // policy (e.g. redirects, cookies, auth) as configured on the client.
 //
@@ -121,6 +262,12 @@ func (r *RequestClient) Do(req *http.Request) (*http.Response, error) {
 	return r.Client.Do(req)
 }
 
+// DoContext - same as Do, request is sent with ctx, cancellation and
+// deadline of ctx reach retries, DNS lookup and dialing
+func (r *RequestClient) DoContext(ctx context.Context, req *http.Request) (*http.Response, error) {
+	return r.Do(req.WithContext(ctx))
+}
+
 // RoundTrip implements the RoundTripper interface.
 //
 // For higher-level HTTP client support (such as handling of cookies
@@ -128,3 +275,8 @@ func (r *RequestClient) Do(req *http.Request) (*http.Response, error) {
 func (r RequestClient) RoundTrip(req *http.Request) (*http.Response, error) {
 	return r.Transport.RoundTrip(req)
 }
+
+// RoundTripContext - same as RoundTrip, request is sent with ctx
+func (r *RequestClient) RoundTripContext(ctx context.Context, req *http.Request) (*http.Response, error) {
+	return r.RoundTrip(req.WithContext(ctx))
+}
@@ -0,0 +1,76 @@
+package requestclient
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDoContextCancelled(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer ts.Close()
+
+	client := New(nil)
+	u, _ := url.Parse(ts.URL)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.DoContext(ctx, client.GET(u)); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled got %v", err)
+	}
+	if _, err := client.RoundTripContext(ctx, client.GET(u)); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled got %v", err)
+	}
+	if n := atomic.LoadInt32(&hits); n != 0 {
+		t.Errorf("Expected no requests to reach server got %d", n)
+	}
+}
+
+func TestLongLivedClientDials(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	op := NewOptions()
+	op.DialerDeadline = 50 * time.Millisecond
+	op.TransportDisableKeepAlives = true
+	client := New(op)
+	for i := 0; i < 3; i++ {
+		res, err := client.Request(GET, ts.URL).Do()
+		if err != nil {
+			t.Fatalf("Expected dial %d to get its own deadline budget got %v", i, err)
+		}
+		res.Body.Close()
+		time.Sleep(60 * time.Millisecond)
+	}
+}
+
+func TestConnectToKeepsHostAndServerName(t *testing.T) {
+	var host, serverName string
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, serverName = r.Host, r.TLS.ServerName
+	}))
+	defer ts.Close()
+	_, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+
+	op := NewOptions()
+	op.TLSInsecureSkipVerify = true
+	op.ConnectTo = map[string]string{"API.example.com:443": "backend.test:" + port}
+	op.HostOverrides = map[string][]string{"backend.test:" + port: {"127.0.0.1"}}
+	client := New(op)
+	res, err := client.Request(GET, "https://api.example.com/").Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if host != "api.example.com" || serverName != "api.example.com" {
+		t.Errorf("Expected requested Host and SNI got %q %q", host, serverName)
+	}
+}
@@ -0,0 +1,77 @@
+package requestclient
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// StatusErrorBodyLimit - max bytes of response body kept in StatusError
+const StatusErrorBodyLimit = 4 << 10
+
+// drainLimit - max bytes read from unconsumed response body before close,
+// draining lets Transport re-use keep-alive connection
+const drainLimit = 64 << 10
+
+// StatusError - response status code was not expected
+type StatusError struct {
+	StatusCode int
+	Status     string
+
+	// Body - beginning of response body, up to StatusErrorBodyLimit bytes
+	Body []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("Unexpected response status: %s", e.Status)
+}
+
+// DecodeResponse - decodes response body into v with codec picked from
+// response Content-Type. Response status must be one of expected, any 2xx
+// if none given, otherwise *StatusError is returned. Nil v only checks
+// status. Response body is always drained and closed.
+func DecodeResponse(res *http.Response, v interface{}, expected ...int) (err error) {
+	defer func() {
+		io.Copy(ioutil.Discard, io.LimitReader(res.Body, drainLimit))
+		res.Body.Close()
+	}()
+	if !expectedStatus(res.StatusCode, expected) {
+		body, _ := ioutil.ReadAll(io.LimitReader(res.Body, StatusErrorBodyLimit))
+		return &StatusError{
+			StatusCode: res.StatusCode,
+			Status:     res.Status,
+			Body:       body,
+		}
+	}
+	if v == nil || res.StatusCode == http.StatusNoContent || res.ContentLength == 0 {
+		return nil
+	}
+	contentType := res.Header.Get("Content-Type")
+	c, ok := CodecFor(contentType)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnsupportedContentType, contentType)
+	}
+	return c.Decode(res.Body, v)
+}
+
+// DoInto - sends request and decodes response into v, see DecodeResponse
+func (r *RequestClient) DoInto(req *http.Request, v interface{}, expected ...int) error {
+	res, err := r.Do(req)
+	if err != nil {
+		return err
+	}
+	return DecodeResponse(res, v, expected...)
+}
+
+func expectedStatus(code int, expected []int) bool {
+	if len(expected) == 0 {
+		return code >= 200 && code < 300
+	}
+	for _, e := range expected {
+		if code == e {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package requestclient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDoInto(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, "not found")
+			return
+		}
+		w.Header().Set("Content-Type", "application/problem+json")
+		fmt.Fprint(w, `{"name":"gopher"}`)
+	}))
+	defer ts.Close()
+
+	client := New(nil)
+	var v struct{ Name string }
+	if err := client.Request(GET, ts.URL).DoInto(&v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Name != "gopher" {
+		t.Errorf("Expected name gopher got %s", v.Name)
+	}
+
+	err := client.Request(GET, ts.URL+"/missing").DoInto(&v, http.StatusOK)
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("Expected *StatusError 404 got %v", err)
+	}
+	if string(statusErr.Body) != "not found" {
+		t.Errorf("Expected body 'not found' got %s", statusErr.Body)
+	}
+}
@@ -0,0 +1,241 @@
+package requestclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/linkosmos/requestclient/transport"
+)
+
+// RetryPolicy default values
+const (
+	DefaultRetryBaseWait  = 100 * time.Millisecond
+	DefaultRetryMaxWait   = 10 * time.Second
+	DefaultRetryAfterWait = 1 * time.Minute
+)
+
+// RetryPolicy - decides whether attempt is retried and how long to wait
+// before the next one. It's called after every attempt, try is 0 for the
+// initial attempt. Either res or err is nil.
+type RetryPolicy interface {
+	Retry(req *http.Request, res *http.Response, err error, try uint) (wait time.Duration, retry bool)
+}
+
+// BackoffPolicy - RetryPolicy retrying idempotent methods on network errors
+// and retryable response statuses, waiting with exponential backoff and
+// full jitter or as requested by response Retry-After header.
+type BackoffPolicy struct {
+	// MaxTries - max number of retries after the initial attempt
+	MaxTries uint
+
+	// BaseWait - backoff of the first retry, doubled with every retry up
+	// to MaxWait, actual wait is random in [0, backoff)
+	BaseWait, MaxWait time.Duration
+
+	// MaxRetryAfter - longest Retry-After honored, longer is not retried
+	MaxRetryAfter time.Duration
+
+	// Methods - retried request methods
+	Methods map[string]bool
+
+	// Statuses - retried response status codes
+	Statuses map[int]bool
+}
+
+// NewBackoffPolicy - returns BackoffPolicy retrying idempotent methods on
+// 429, 502, 503 and 504 statuses
+func NewBackoffPolicy(maxTries uint) *BackoffPolicy {
+	return &BackoffPolicy{
+		MaxTries:      maxTries,
+		BaseWait:      DefaultRetryBaseWait,
+		MaxWait:       DefaultRetryMaxWait,
+		MaxRetryAfter: DefaultRetryAfterWait,
+		Methods: map[string]bool{
+			GET:     true,
+			HEAD:    true,
+			PUT:     true,
+			DELETE:  true,
+			OPTIONS: true,
+		},
+		Statuses: map[int]bool{
+			http.StatusTooManyRequests:    true,
+			http.StatusBadGateway:         true,
+			http.StatusServiceUnavailable: true,
+			http.StatusGatewayTimeout:     true,
+		},
+	}
+}
+
+// Retry - implements RetryPolicy
+func (p *BackoffPolicy) Retry(req *http.Request, res *http.Response, err error, try uint) (time.Duration, bool) {
+	if try >= p.MaxTries || !p.Methods[req.Method] {
+		return 0, false
+	}
+	if err != nil {
+		return p.backoff(try), RetryableError(err)
+	}
+	if !p.Statuses[res.StatusCode] {
+		return 0, false
+	}
+	if wait, ok := RetryAfter(res); ok {
+		return wait, wait <= p.MaxRetryAfter
+	}
+	return p.backoff(try), true
+}
+
+// backoff - full jitter, random in [0, min(MaxWait, BaseWait * 2^try))
+func (p *BackoffPolicy) backoff(try uint) time.Duration {
+	ceil := p.BaseWait << try
+	if ceil <= 0 || ceil > p.MaxWait {
+		ceil = p.MaxWait
+	}
+	if ceil <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceil)))
+}
+
+// RetryableError - reports whether err is a network error probably safe to
+// retry, cancelled or expired request context is never retryable
+func RetryableError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// RetryAfter - parses response Retry-After header given in seconds or as
+// HTTP date
+func RetryAfter(res *http.Response) (time.Duration, bool) {
+	v := res.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		wait := time.Until(t)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
+
+func retryMiddleware(policy RetryPolicy, stats func(*Stats)) Middleware {
+	return func(next RoundTripper) RoundTripper {
+		return &retryTransport{next: next, policy: policy, stats: stats}
+	}
+}
+
+// retryTransport - retries attempts as decided by RetryPolicy, request body
+// is rewound with Request.GetBody between attempts. Stats of every attempt
+// are reported to stats with retry count, retried ones as pending.
+type retryTransport struct {
+	next   RoundTripper
+	policy RetryPolicy
+	stats  func(*Stats)
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rewindable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	attempt := req
+	for try := uint(0); ; try++ {
+		stats := &attemptStats{try: try, report: t.stats}
+		if t.stats != nil {
+			attempt = attempt.WithContext(transport.WithStats(attempt.Context(), stats.observe))
+		}
+		res, err := t.next.RoundTrip(attempt)
+		if !rewindable {
+			stats.decide(false)
+			return res, err
+		}
+		wait, retry := t.policy.Retry(req, res, err, try)
+		if !retry {
+			stats.decide(false)
+			return res, err
+		}
+		if res != nil {
+			io.Copy(ioutil.Discard, io.LimitReader(res.Body, drainLimit))
+			res.Body.Close()
+		}
+		stats.decide(true)
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+		if attempt, err = rewind(req); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// attemptStats - holds Stats of an attempt until retry is decided
+type attemptStats struct {
+	try    uint
+	report func(*Stats)
+
+	mu      sync.Mutex
+	decided bool
+	held    *Stats
+}
+
+// observe - receives Stats from Transport, errors are reported before
+// RoundTrip returns, responses once body is closed
+func (a *attemptStats) observe(s *Stats) {
+	s.Retry.Count = a.try
+	a.mu.Lock()
+	if !a.decided {
+		a.held = s
+		a.mu.Unlock()
+		return
+	}
+	a.mu.Unlock()
+	a.report(s)
+}
+
+// decide - reports held Stats, pending if attempt is retried
+func (a *attemptStats) decide(retry bool) {
+	a.mu.Lock()
+	a.decided = true
+	s := a.held
+	a.held = nil
+	a.mu.Unlock()
+	if s != nil {
+		s.Retry.Pending = retry
+		a.report(s)
+	}
+}
+
+// rewind - returns shallow copy of req with fresh body
+func rewind(req *http.Request) (*http.Request, error) {
+	if req.GetBody == nil {
+		return req, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	r := req.WithContext(req.Context())
+	r.Body = body
+	return r, nil
+}
@@ -0,0 +1,136 @@
+package requestclient
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/linkosmos/requestclient/breaker"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if string(body) != "payload" {
+			t.Errorf("Expected body payload on every attempt got %q", body)
+		}
+		if atomic.AddInt32(&hits, 1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	op := NewOptions()
+	policy := NewBackoffPolicy(2)
+	policy.BaseWait = time.Millisecond
+	op.RetryPolicy = policy
+	client := New(op)
+
+	res, err := client.Request(PUT, ts.URL).Body("payload").Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || atomic.LoadInt32(&hits) != 3 {
+		t.Errorf("Expected 200 after 3 attempts got %d after %d", res.StatusCode, hits)
+	}
+
+	atomic.StoreInt32(&hits, 0)
+	res, err = client.Request(POST, ts.URL).Body("payload").Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusServiceUnavailable || atomic.LoadInt32(&hits) != 1 {
+		t.Errorf("Expected POST not to be retried got %d after %d", res.StatusCode, hits)
+	}
+}
+
+func TestRetryThroughBreaker(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	op := NewOptions()
+	policy := NewBackoffPolicy(5)
+	policy.BaseWait = time.Millisecond
+	op.RetryPolicy = policy
+	op.CircuitBreaker = breaker.NewConfig()
+	op.CircuitBreaker.MinRequests = 2
+	client := New(op)
+
+	if _, err := client.Request(GET, ts.URL).Do(); !errors.Is(err, breaker.ErrOpen) {
+		t.Errorf("Expected retries to be stopped by open breaker got %v", err)
+	}
+	if n := atomic.LoadInt32(&hits); n != 2 {
+		t.Errorf("Expected 2 attempts before breaker opened got %d", n)
+	}
+}
+
+func TestBreakerDisablesTransportRetries(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	defer ts.Close()
+
+	op := NewOptions()
+	op.CircuitBreaker = breaker.NewConfig()
+	client := New(op)
+	if _, err := client.Request(GET, ts.URL).Do(); err == nil {
+		t.Fatal("Expected connection error")
+	}
+	if n := atomic.LoadInt32(&hits); n != 1 {
+		t.Errorf("Expected single attempt through breaker got %d", n)
+	}
+}
+
+func TestRetryPolicyStats(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	var mu sync.Mutex
+	var stats []*Stats
+	op := NewOptions()
+	policy := NewBackoffPolicy(3)
+	policy.BaseWait = time.Millisecond
+	op.RetryPolicy = policy
+	op.OnStats = func(s *Stats) {
+		mu.Lock()
+		stats = append(stats, s)
+		mu.Unlock()
+	}
+	res, err := New(op).Request(GET, ts.URL).Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(stats) != 3 {
+		t.Fatalf("Expected stats of 3 attempts got %d", len(stats))
+	}
+	for i, s := range stats {
+		if pending := i < 2; s.Retry.Pending != pending || s.Retry.Count != uint(i) {
+			t.Errorf("Attempt %d: expected pending %v got %v with count %d", i, pending, s.Retry.Pending, s.Retry.Count)
+		}
+	}
+}
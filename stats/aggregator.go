@@ -0,0 +1,213 @@
+package stats
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/linkosmos/requestclient/transport"
+)
+
+// DefaultBuckets - latency histogram upper bounds in seconds
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// ErrorClass - status class of requests that got no response
+const ErrorClass = "error"
+
+// Key - aggregation key
+type Key struct {
+	Host, Method string
+
+	// Class - response status class such as 2xx, or ErrorClass
+	Class string
+}
+
+// Aggregate - statistics of a single Key
+type Aggregate struct {
+	// Count - completed requests, retried attempts are not counted
+	Count uint64
+
+	// Errors - completed requests that failed without response
+	Errors uint64
+
+	// Retries - attempts that failed and were retried
+	Retries uint64
+
+	// Header - time to response headers, Total - time to response body
+	// close or error
+	Header, Total *Histogram
+}
+
+// Histogram - cumulative latency histogram
+type Histogram struct {
+	Buckets []float64 // upper bounds in seconds
+	Counts  []uint64  // cumulative count of each bucket
+	Sum     float64   // seconds
+	Count   uint64
+}
+
+func newHistogram(buckets []float64) *Histogram {
+	return &Histogram{
+		Buckets: buckets,
+		Counts:  make([]uint64, len(buckets)),
+	}
+}
+
+func (h *Histogram) observe(d time.Duration) {
+	s := d.Seconds()
+	for i, upper := range h.Buckets {
+		if s <= upper {
+			h.Counts[i]++
+		}
+	}
+	h.Sum += s
+	h.Count++
+}
+
+func (h *Histogram) clone() *Histogram {
+	c := *h
+	c.Counts = append([]uint64(nil), h.Counts...)
+	return &c
+}
+
+// Aggregator - aggregates transport.Stats per host, method and status
+// class, use Observe as Options.OnStats
+type Aggregator struct {
+	// Namespace - prefix of exposed metric names
+	Namespace string
+
+	buckets []float64
+
+	mu         sync.Mutex
+	aggregates map[Key]*Aggregate
+}
+
+// NewAggregator - returns empty Aggregator with DefaultBuckets
+func NewAggregator() *Aggregator {
+	return &Aggregator{
+		Namespace:  "requestclient",
+		buckets:    DefaultBuckets,
+		aggregates: make(map[Key]*Aggregate),
+	}
+}
+
+// Observe - records request stats, it's safe for concurrent use
+func (a *Aggregator) Observe(s *transport.Stats) {
+	key := Key{
+		Host:   s.Request.URL.Host,
+		Method: s.Request.Method,
+		Class:  ErrorClass,
+	}
+	if s.Response != nil {
+		key.Class = strconv.Itoa(s.Response.StatusCode/100) + "xx"
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	agg, ok := a.aggregates[key]
+	if !ok {
+		agg = &Aggregate{
+			Header: newHistogram(a.buckets),
+			Total:  newHistogram(a.buckets),
+		}
+		a.aggregates[key] = agg
+	}
+	if s.Retry.Pending {
+		agg.Retries++
+		return
+	}
+	agg.Count++
+	if s.Error != nil {
+		agg.Errors++
+	}
+	agg.Header.observe(s.Duration.Header)
+	agg.Total.observe(s.Duration.Header + s.Duration.Body)
+}
+
+// Snapshot - returns copy of all aggregates
+func (a *Aggregator) Snapshot() map[Key]Aggregate {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	snapshot := make(map[Key]Aggregate, len(a.aggregates))
+	for k, agg := range a.aggregates {
+		c := *agg
+		c.Header, c.Total = agg.Header.clone(), agg.Total.clone()
+		snapshot[k] = c
+	}
+	return snapshot
+}
+
+// ServeHTTP - renders aggregates in Prometheus text exposition format
+func (a *Aggregator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	a.WriteTo(w)
+}
+
+// WriteTo - writes aggregates in Prometheus text exposition format
+func (a *Aggregator) WriteTo(w io.Writer) (int64, error) {
+	snapshot := a.Snapshot()
+	keys := make([]Key, 0, len(snapshot))
+	for k := range snapshot {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Host != keys[j].Host {
+			return keys[i].Host < keys[j].Host
+		}
+		if keys[i].Method != keys[j].Method {
+			return keys[i].Method < keys[j].Method
+		}
+		return keys[i].Class < keys[j].Class
+	})
+
+	var b strings.Builder
+	counters := []struct {
+		name, help string
+		value      func(Aggregate) uint64
+	}{
+		{"requests_total", "Completed requests.", func(a Aggregate) uint64 { return a.Count }},
+		{"errors_total", "Requests failed without response.", func(a Aggregate) uint64 { return a.Errors }},
+		{"retries_total", "Failed attempts that were retried.", func(a Aggregate) uint64 { return a.Retries }},
+	}
+	for _, c := range counters {
+		name := a.Namespace + "_" + c.name
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n", name, c.help, name)
+		for _, k := range keys {
+			fmt.Fprintf(&b, "%s{%s} %d\n", name, labels(k), c.value(snapshot[k]))
+		}
+	}
+	histograms := []struct {
+		name, help string
+		value      func(Aggregate) *Histogram
+	}{
+		{"response_header_seconds", "Time to response headers.", func(a Aggregate) *Histogram { return a.Header }},
+		{"request_duration_seconds", "Time to response body close or error.", func(a Aggregate) *Histogram { return a.Total }},
+	}
+	for _, h := range histograms {
+		name := a.Namespace + "_" + h.name
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s histogram\n", name, h.help, name)
+		for _, k := range keys {
+			hist, l := h.value(snapshot[k]), labels(k)
+			for i, upper := range hist.Buckets {
+				fmt.Fprintf(&b, "%s_bucket{%s,le=\"%s\"} %d\n", name, l,
+					strconv.FormatFloat(upper, 'g', -1, 64), hist.Counts[i])
+			}
+			fmt.Fprintf(&b, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, l, hist.Count)
+			fmt.Fprintf(&b, "%s_sum{%s} %s\n", name, l, strconv.FormatFloat(hist.Sum, 'g', -1, 64))
+			fmt.Fprintf(&b, "%s_count{%s} %d\n", name, l, hist.Count)
+		}
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func labels(k Key) string {
+	return fmt.Sprintf(`host="%s",method="%s",class="%s"`,
+		labelEscaper.Replace(k.Host), labelEscaper.Replace(k.Method), labelEscaper.Replace(k.Class))
+}
@@ -0,0 +1,49 @@
+package stats
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/linkosmos/requestclient/transport"
+)
+
+func TestAggregator(t *testing.T) {
+	a := NewAggregator()
+	req := &http.Request{Method: "GET", URL: &url.URL{Host: "example.com"}}
+
+	ok := &transport.Stats{Request: req, Response: &http.Response{StatusCode: 200}}
+	ok.Duration.Header = 20 * time.Millisecond
+	ok.Duration.Body = 10 * time.Millisecond
+	a.Observe(ok)
+
+	retried := &transport.Stats{Request: req, Error: errors.New("connection reset")}
+	retried.Retry.Pending = true
+	a.Observe(retried)
+	a.Observe(&transport.Stats{Request: req, Error: errors.New("connection reset")})
+
+	snapshot := a.Snapshot()
+	if got := snapshot[Key{"example.com", "GET", "2xx"}]; got.Count != 1 || got.Total.Count != 1 {
+		t.Errorf("Expected single 2xx request got %+v", got)
+	}
+	if got := snapshot[Key{"example.com", "GET", ErrorClass}]; got.Errors != 1 || got.Retries != 1 {
+		t.Errorf("Expected 1 error and 1 retry got %+v", got)
+	}
+
+	var buf bytes.Buffer
+	a.WriteTo(&buf)
+	for _, line := range []string{
+		`requestclient_requests_total{host="example.com",method="GET",class="2xx"} 1`,
+		`requestclient_request_duration_seconds_bucket{host="example.com",method="GET",class="2xx",le="0.025"} 0`,
+		`requestclient_request_duration_seconds_bucket{host="example.com",method="GET",class="2xx",le="0.05"} 1`,
+		`requestclient_retries_total{host="example.com",method="GET",class="error"} 1`,
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("Expected exposition to contain %s", line)
+		}
+	}
+}
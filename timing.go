@@ -0,0 +1,183 @@
+package requestclient
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/linkosmos/requestclient/dialer"
+)
+
+// Timing - durations of request phases, zero when phase did not happen,
+// e.g. DNS lookup and connect are zero for re-used connections
+type Timing struct {
+	// DNSLookup - name resolution, DNSCacheHit is true when dialer
+	// resolved from its address cache
+	DNSLookup   time.Duration
+	DNSCacheHit bool
+
+	// Connect - TCP connection setup
+	Connect time.Duration
+
+	// TLSHandshake - TLS handshake of https requests
+	TLSHandshake time.Duration
+
+	// FirstByte - time from request start to first response byte
+	FirstByte time.Duration
+
+	// BodyTransfer - time from first response byte to body EOF or close
+	BodyTransfer time.Duration
+
+	// Total - time from request start to body EOF, close or error
+	Total time.Duration
+
+	// ConnReused - connection was re-used from idle pool
+	ConnReused bool
+}
+
+type timingKey struct{}
+
+type timingCallbackKey struct{}
+
+// TimingOf - returns timing of response, body transfer and total are set
+// once body is read to EOF or closed
+func TimingOf(res *http.Response) (Timing, bool) {
+	if res == nil || res.Request == nil {
+		return Timing{}, false
+	}
+	t, ok := res.Request.Context().Value(timingKey{}).(*timing)
+	if !ok {
+		return Timing{}, false
+	}
+	return t.snapshot(), true
+}
+
+// WithTimingCallback - returns context calling fn with request timing once
+// response body is read to EOF or closed, or request fails
+func WithTimingCallback(ctx context.Context, fn func(Timing)) context.Context {
+	return context.WithValue(ctx, timingCallbackKey{}, fn)
+}
+
+// timing - collects phase timestamps, trace hooks may be called from
+// dialing goroutines
+type timing struct {
+	mu sync.Mutex
+	t  Timing
+
+	start, dnsStart, connectStart, tlsStart, firstByte time.Time
+	done                                               bool
+
+	callback func(Timing)
+}
+
+func (t *timing) snapshot() Timing {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.t
+}
+
+func (t *timing) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.set(func() { t.dnsStart = time.Now() })
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.set(func() { t.t.DNSLookup = time.Since(t.dnsStart) })
+		},
+		ConnectStart: func(network, addr string) {
+			t.set(func() { t.connectStart = time.Now() })
+		},
+		ConnectDone: func(network, addr string, err error) {
+			t.set(func() { t.t.Connect = time.Since(t.connectStart) })
+		},
+		TLSHandshakeStart: func() {
+			t.set(func() { t.tlsStart = time.Now() })
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.set(func() { t.t.TLSHandshake = time.Since(t.tlsStart) })
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.set(func() { t.t.ConnReused = info.Reused })
+		},
+		GotFirstResponseByte: func() {
+			t.set(func() {
+				t.firstByte = time.Now()
+				t.t.FirstByte = t.firstByte.Sub(t.start)
+			})
+		},
+	}
+}
+
+func (t *timing) set(fn func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fn()
+}
+
+// finish - records end of request once, calls callback
+func (t *timing) finish() {
+	t.mu.Lock()
+	if t.done {
+		t.mu.Unlock()
+		return
+	}
+	t.done = true
+	now := time.Now()
+	if !t.firstByte.IsZero() {
+		t.t.BodyTransfer = now.Sub(t.firstByte)
+	}
+	t.t.Total = now.Sub(t.start)
+	timing := t.t
+	t.mu.Unlock()
+	if t.callback != nil {
+		t.callback(timing)
+	}
+}
+
+// timingTransport - innermost layer tracing every attempt
+type timingTransport struct {
+	next RoundTripper
+}
+
+func (tt *timingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	t := &timing{start: time.Now()}
+	t.callback, _ = ctx.Value(timingCallbackKey{}).(func(Timing))
+	ctx = context.WithValue(ctx, timingKey{}, t)
+	ctx = httptrace.WithClientTrace(ctx, t.trace())
+	ctx = dialer.WithTrace(ctx, &dialer.Trace{
+		DNSCacheHit: func(host string, hit bool) {
+			t.set(func() { t.t.DNSCacheHit = hit })
+		},
+	})
+	res, err := tt.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		t.finish()
+		return res, err
+	}
+	res.Body = &timingBody{ReadCloser: res.Body, timing: t}
+	return res, nil
+}
+
+type timingBody struct {
+	io.ReadCloser
+	timing *timing
+}
+
+func (b *timingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.timing.finish()
+	}
+	return n, err
+}
+
+func (b *timingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.timing.finish()
+	return err
+}
@@ -0,0 +1,48 @@
+package requestclient
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestTiming(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("body"))
+	}))
+	defer ts.Close()
+
+	client := New(nil)
+	u, _ := url.Parse(ts.URL)
+	var callback Timing
+	ctx := WithTimingCallback(context.Background(), func(t Timing) {
+		callback = t
+	})
+	for i, reused := range []bool{false, true} {
+		res, err := client.DoContext(ctx, client.GET(u))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		timing, ok := TimingOf(res)
+		if !ok {
+			t.Fatal("Expected response to carry timing")
+		}
+		if timing.ConnReused != reused {
+			t.Errorf("Request %d: expected ConnReused %t got %t", i, reused, timing.ConnReused)
+		}
+		if !reused && timing.Connect == 0 {
+			t.Errorf("Request %d: expected connect duration", i)
+		}
+		if timing.FirstByte == 0 || timing.Total < timing.FirstByte {
+			t.Errorf("Request %d: expected FirstByte <= Total got %+v", i, timing)
+		}
+		if callback != timing {
+			t.Errorf("Request %d: expected callback timing %+v got %+v", i, timing, callback)
+		}
+	}
+}
@@ -1,14 +1,13 @@
-// Package httpcontrol allows a HTTP transport supporting connection pooling,
-// timeouts & retries.
-//
-// This Transport is built on top of the standard library transport and
-// augments it with additional features.
-package httpcontrol
+// Package transport - http.Transport with request timeout, retries of
+// known safe failures and per request statistics, successor of
+// github.com/facebookgo/httpcontrol honoring request context. Portions
+// derived from httpcontrol, see LICENSE.httpcontrol.
+package transport
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
-	"flag"
 	"fmt"
 	"io"
 	"net"
@@ -20,7 +19,7 @@ import (
 	"time"
 )
 
-// Stats for a RoundTrip.
+// Stats - statistics of a single round trip
 type Stats struct {
 	// The RoundTrip request.
 	Request *http.Request
@@ -49,28 +48,23 @@ type Stats struct {
 	}
 }
 
-// A human readable representation often useful for debugging.
+// String - human readable representation often useful for debugging
 func (s *Stats) String() string {
 	var buf bytes.Buffer
 	fmt.Fprintf(&buf, "%s %s", s.Request.Method, s.Request.URL)
-
 	if s.Response != nil {
 		fmt.Fprintf(&buf, " got response with status %s", s.Response.Status)
 	}
-
 	return buf.String()
 }
 
-// Transport is an implementation of RoundTripper that supports http, https,
-// and http proxies (for either http or https with CONNECT). Transport can
-// cache connections for future re-use, provides various timeouts, retry logic
-// and the ability to track request statistics.
+// Transport - http.Transport supporting connection pooling, timeouts,
+// retries and request statistics. Request context cancellation and
+// deadline reach dialing and stop retries.
 type Transport struct {
-
 	// Proxy specifies a function to return a proxy for a given
-	// *http.Request. If the function returns a non-nil error, the
-	// request is aborted with the provided error.
-	// If Proxy is nil or returns a nil *url.URL, no proxy is used.
+	// *http.Request. If Proxy is nil or returns a nil *url.URL, no proxy
+	// is used.
 	Proxy func(*http.Request) (*url.URL, error)
 
 	// TLSClientConfig specifies the TLS configuration to use with
@@ -82,40 +76,28 @@ type Transport struct {
 	DisableKeepAlives bool
 
 	// DisableCompression, if true, prevents the Transport from
-	// requesting compression with an "Accept-Encoding: gzip"
-	// request header when the Request contains no existing
-	// Accept-Encoding value. If the Transport requests gzip on
-	// its own and gets a gzipped response, it's transparently
-	// decoded in the Response.Body. However, if the user
-	// explicitly requested gzip it is not automatically
-	// uncompressed.
+	// requesting compression with an "Accept-Encoding: gzip".
 	DisableCompression bool
 
 	// MaxIdleConnsPerHost, if non-zero, controls the maximum idle
-	// (keep-alive) to keep per-host.  If zero,
+	// (keep-alive) to keep per-host. If zero,
 	// http.DefaultMaxIdleConnsPerHost is used.
 	MaxIdleConnsPerHost int
 
-	// Dial connects to the address on the named network.
-	//
-	// See func Dial for a description of the network and address
-	// parameters.
-	Dial func(network, address string) (net.Conn, error)
-
-	// Timeout is the maximum amount of time a dial will wait for
-	// a connect to complete.
-	//
-	// The default is no timeout.
-	//
-	// With or without a timeout, the operating system may impose
-	// its own earlier timeout. For instance, TCP timeouts are
-	// often around 3 minutes.
+	// IdleConnTimeout, if non-zero, is the maximum amount of time an idle
+	// (keep-alive) connection will remain idle before closing itself.
+	IdleConnTimeout time.Duration
+
+	// DialContext connects to the address on the named network, if nil
+	// net.Dialer with DialTimeout and DialKeepAlive is used.
+	DialContext func(ctx context.Context, network, address string) (net.Conn, error)
+
+	// DialTimeout is the maximum amount of time a dial will wait for
+	// a connect to complete, used only without DialContext.
 	DialTimeout time.Duration
 
 	// DialKeepAlive specifies the keep-alive period for an active
-	// network connection.
-	// If zero, keep-alives are not enabled. Network protocols
-	// that do not support keep-alives ignore this field.
+	// network connection, used only without DialContext.
 	DialKeepAlive time.Duration
 
 	// ResponseHeaderTimeout, if non-zero, specifies the amount of
@@ -131,20 +113,17 @@ type Transport struct {
 
 	// RetryAfterTimeout, if true, will enable retries for a number of failures
 	// that are probably safe to retry for most cases but, depending on the
-	// context, might not be safe. Retried errors: net.Errors where Timeout()
-	// returns `true` or timeouts that bubble up as url.Error but were originally
-	// net.Error, OpErrors where the request was cancelled (either by this lib or
-	// by the calling code, or finally errors from requests that were cancelled
-	// before the remote side was contacted.
+	// context, might not be safe, e.g. timeouts.
 	RetryAfterTimeout bool
 
 	// MaxTries, if non-zero, specifies the number of times we will retry on
-	// failure. Retries are only attempted for temporary network errors or known
-	// safe failures.
+	// failure. Retries are only attempted for GET requests failing with
+	// temporary network errors or known safe failures, never once request
+	// context is done.
 	MaxTries uint
 
 	// Stats allows for capturing the result of a request and is useful for
-	// monitoring purposes.
+	// monitoring purposes, see also WithStats.
 	Stats func(*Stats)
 
 	startOnce sync.Once
@@ -172,8 +151,6 @@ func (t *Transport) shouldRetryError(err error) bool {
 		if neterr, ok := err.(net.Error); ok && neterr.Timeout() {
 			return true
 		}
-
-		// http://stackoverflow.com/questions/23494950/specifically-check-for-timeout-error
 		if urlerr, ok := err.(*url.Error); ok {
 			if neturlerr, ok := urlerr.Err.(net.Error); ok && neturlerr.Timeout() {
 				return true
@@ -184,11 +161,6 @@ func (t *Transport) shouldRetryError(err error) bool {
 				return true
 			}
 		}
-
-		// The request timed out before we could connect
-		if strings.Contains(err.Error(), "request canceled while waiting for connection") {
-			return true
-		}
 	}
 
 	s := err.Error()
@@ -200,57 +172,52 @@ func (t *Transport) shouldRetryError(err error) bool {
 	return false
 }
 
-// Start the Transport.
 func (t *Transport) start() {
-	if t.Dial == nil {
+	dial := t.DialContext
+	if dial == nil {
 		dialer := &net.Dialer{
 			Timeout:   t.DialTimeout,
 			KeepAlive: t.DialKeepAlive,
 		}
-		t.Dial = dialer.Dial
+		dial = dialer.DialContext
 	}
 	t.transport = &http.Transport{
-		Dial:                  t.Dial,
+		DialContext:           dial,
 		Proxy:                 t.Proxy,
 		TLSClientConfig:       t.TLSClientConfig,
 		DisableKeepAlives:     t.DisableKeepAlives,
 		DisableCompression:    t.DisableCompression,
 		MaxIdleConnsPerHost:   t.MaxIdleConnsPerHost,
+		IdleConnTimeout:       t.IdleConnTimeout,
 		ResponseHeaderTimeout: t.ResponseHeaderTimeout,
 	}
 }
 
-// CloseIdleConnections closes the idle connections.
+// CloseIdleConnections - closes idle connections
 func (t *Transport) CloseIdleConnections() {
 	t.startOnce.Do(t.start)
 	t.transport.CloseIdleConnections()
 }
 
-// CancelRequest cancels an in-flight request by closing its connection.
-func (t *Transport) CancelRequest(req *http.Request) {
+// RoundTrip - implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 	t.startOnce.Do(t.start)
-	if bc, ok := req.Body.(*bodyCloser); ok {
-		bc.timer.Stop()
-	}
-	t.transport.CancelRequest(req)
+	return t.tries(req, 0)
 }
 
 func (t *Transport) tries(req *http.Request, try uint) (*http.Response, error) {
 	startTime := time.Now()
-	var timer *time.Timer
+	ctx, cancel := req.Context(), context.CancelFunc(func() {})
 	if t.RequestTimeout != 0 {
-		timer = time.AfterFunc(t.RequestTimeout, func() {
-			t.CancelRequest(req)
-		})
+		ctx, cancel = context.WithTimeout(ctx, t.RequestTimeout)
 	}
-	res, err := t.transport.RoundTrip(req)
+	res, err := t.transport.RoundTrip(req.WithContext(ctx))
 	headerTime := time.Now()
+	report := t.statsFunc(req)
 	if err != nil {
-		if timer != nil {
-			timer.Stop()
-		}
+		cancel()
 		var stats *Stats
-		if t.Stats != nil {
+		if report != nil {
 			stats = &Stats{
 				Request:  req,
 				Response: res,
@@ -260,120 +227,72 @@ func (t *Transport) tries(req *http.Request, try uint) (*http.Response, error) {
 			stats.Retry.Count = try
 		}
 
-		if try < t.MaxTries && req.Method == "GET" && t.shouldRetryError(err) {
-			if t.Stats != nil {
+		// Cancelled or expired request context is never retried
+		if try < t.MaxTries && req.Method == http.MethodGet && req.Context().Err() == nil && t.shouldRetryError(err) {
+			if report != nil {
 				stats.Retry.Pending = true
-				t.Stats(stats)
+				report(stats)
 			}
 			return t.tries(req, try+1)
 		}
 
-		if t.Stats != nil {
-			t.Stats(stats)
+		if report != nil {
+			report(stats)
 		}
 		return nil, err
 	}
 
 	res.Body = &bodyCloser{
 		ReadCloser: res.Body,
-		timer:      timer,
+		cancel:     cancel,
 		res:        res,
-		transport:  t,
+		report:     report,
 		startTime:  startTime,
 		headerTime: headerTime,
+		try:        try,
 	}
 	return res, nil
 }
 
-// RoundTrip implements the RoundTripper interface.
-func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
-	t.startOnce.Do(t.start)
-	return t.tries(req, 0)
+type statsKey struct{}
+
+// WithStats - returns ctx of requests reporting their Stats to fn instead
+// of Transport.Stats, e.g. so retries decided above Transport can mark
+// attempts as pending
+func WithStats(ctx context.Context, fn func(*Stats)) context.Context {
+	return context.WithValue(ctx, statsKey{}, fn)
+}
+
+func (t *Transport) statsFunc(req *http.Request) func(*Stats) {
+	if fn, ok := req.Context().Value(statsKey{}).(func(*Stats)); ok {
+		return fn
+	}
+	return t.Stats
 }
 
 type bodyCloser struct {
 	io.ReadCloser
-	timer      *time.Timer
+	cancel     context.CancelFunc
 	res        *http.Response
-	transport  *Transport
+	report     func(*Stats)
 	startTime  time.Time
 	headerTime time.Time
+	try        uint
 }
 
 func (b *bodyCloser) Close() error {
-	if b.timer != nil {
-		b.timer.Stop()
-	}
 	err := b.ReadCloser.Close()
+	b.cancel()
 	closeTime := time.Now()
-	if b.transport.Stats != nil {
+	if b.report != nil {
 		stats := &Stats{
 			Request:  b.res.Request,
 			Response: b.res,
 		}
 		stats.Duration.Header = b.headerTime.Sub(b.startTime)
 		stats.Duration.Body = closeTime.Sub(b.startTime) - stats.Duration.Header
-		b.transport.Stats(stats)
+		stats.Retry.Count = b.try
+		b.report(stats)
 	}
 	return err
 }
-
-// TransportFlag - A Flag configured Transport instance.
-func TransportFlag(name string) *Transport {
-	t := &Transport{TLSClientConfig: &tls.Config{}}
-	flag.BoolVar(
-		&t.TLSClientConfig.InsecureSkipVerify,
-		name+".insecure-tls",
-		false,
-		name+" skip tls certificate verification",
-	)
-	flag.BoolVar(
-		&t.DisableKeepAlives,
-		name+".disable-keepalive",
-		false,
-		name+" disable keep-alives",
-	)
-	flag.BoolVar(
-		&t.DisableCompression,
-		name+".disable-compression",
-		false,
-		name+" disable compression",
-	)
-	flag.IntVar(
-		&t.MaxIdleConnsPerHost,
-		name+".max-idle-conns-per-host",
-		http.DefaultMaxIdleConnsPerHost,
-		name+" max idle connections per host",
-	)
-	flag.DurationVar(
-		&t.DialTimeout,
-		name+".dial-timeout",
-		2*time.Second,
-		name+" dial timeout",
-	)
-	flag.DurationVar(
-		&t.DialKeepAlive,
-		name+".dial-keepalive",
-		0,
-		name+" dial keepalive connection",
-	)
-	flag.DurationVar(
-		&t.ResponseHeaderTimeout,
-		name+".response-header-timeout",
-		3*time.Second,
-		name+" response header timeout",
-	)
-	flag.DurationVar(
-		&t.RequestTimeout,
-		name+".request-timeout",
-		30*time.Second,
-		name+" request timeout",
-	)
-	flag.UintVar(
-		&t.MaxTries,
-		name+".max-tries",
-		0,
-		name+" max retries for known safe failures",
-	)
-	return t
-}
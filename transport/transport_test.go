@@ -0,0 +1,50 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTransportRetries(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close() // EOF, known safe failure
+		}
+	}))
+	defer ts.Close()
+
+	var mu sync.Mutex
+	var stats []*Stats
+	tr := &Transport{
+		MaxTries:          2,
+		Stats:             func(s *Stats) { mu.Lock(); stats = append(stats, s); mu.Unlock() },
+		DisableKeepAlives: true,
+	}
+	req, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
+	res, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	mu.Lock()
+	if len(stats) != 2 || !stats[0].Retry.Pending || stats[1].Retry.Pending || stats[1].Retry.Count != 1 {
+		t.Errorf("Expected pending retry followed by completed request got %+v", stats)
+	}
+	mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	atomic.StoreInt32(&hits, 0)
+	if _, err := tr.RoundTrip(req.WithContext(ctx)); err == nil {
+		t.Error("Expected cancelled request to fail")
+	}
+	if n := atomic.LoadInt32(&hits); n != 0 {
+		t.Errorf("Expected cancelled request not to be retried got %d hits", n)
+	}
+}